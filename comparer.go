@@ -0,0 +1,53 @@
+package snapshot
+
+import "go.followtheprocess.codes/snapshot/internal/compare"
+
+// Comparer is an interface describing something capable of deciding whether a
+// newly generated snapshot matches the one previously saved.
+//
+// Both snapshots are passed exactly as they would be written to disk, that is
+// after formatting and applying any filters, with CRLF line endings in the old
+// snapshot normalised to LF.
+type Comparer interface {
+	// Equal reports whether the old and current snapshots should be considered
+	// a match. An error fails the test, and should be returned if the snapshots
+	// cannot be compared, for example if they are not valid JSON when comparing
+	// them as JSON.
+	Equal(old, current []byte) (bool, error)
+}
+
+// ExactComparer returns a [Comparer] that requires snapshots to be byte for byte
+// identical.
+//
+// This is the default, unless the [Formatter] also implements [Comparer] in which
+// case it is used to compare it's own snapshots. The [InstaFormatter] does this to
+// compare only the snapshot values, ignoring the metadata.
+func ExactComparer() Comparer {
+	return compare.Exact{}
+}
+
+// WhitespaceComparer returns a [Comparer] that considers snapshots equal if they
+// differ only in whitespace, any run of whitespace (including newlines) is equivalent
+// to any other and leading and trailing whitespace is ignored.
+func WhitespaceComparer() Comparer {
+	return compare.Whitespace{}
+}
+
+// JSONComparer returns a [Comparer] that considers snapshots equal if they are
+// semantically equal JSON documents, ignoring key order, whitespace and how
+// numbers are written.
+//
+// This is useful for snapshot files that are hand edited or generated by
+// another tool, and so may not be formatted exactly as snapshot would format them.
+func JSONComparer() Comparer {
+	return compare.JSON{}
+}
+
+// YAMLComparer returns a [Comparer] that considers snapshots equal if they are
+// semantically equal YAML documents, ignoring key order, formatting and scalar style.
+//
+// Snapshots with multiple documents, like the default insta format, are
+// equal if all their documents are equal.
+func YAMLComparer() Comparer {
+	return compare.YAML{}
+}
@@ -1,9 +1,17 @@
 package snapshot
 
 import (
+	"go.followtheprocess.codes/snapshot/internal/format/ansi"
+	"go.followtheprocess.codes/snapshot/internal/format/golit"
+	"go.followtheprocess.codes/snapshot/internal/format/gosource"
+	"go.followtheprocess.codes/snapshot/internal/format/hexdump"
 	"go.followtheprocess.codes/snapshot/internal/format/insta"
 	"go.followtheprocess.codes/snapshot/internal/format/json"
+	"go.followtheprocess.codes/snapshot/internal/format/markup"
+	"go.followtheprocess.codes/snapshot/internal/format/screen"
+	"go.followtheprocess.codes/snapshot/internal/format/table"
 	"go.followtheprocess.codes/snapshot/internal/format/text"
+	"go.followtheprocess.codes/snapshot/internal/format/toml"
 	"go.followtheprocess.codes/snapshot/internal/format/yaml"
 )
 
@@ -19,30 +27,362 @@ type Formatter interface {
 	Ext() string
 }
 
+// Decoder is an optional interface a [Formatter] may implement if the snapshots
+// it produces can be decoded back into structured data.
+//
+// When a snapshot doesn't match and its [Formatter] is also a Decoder, both
+// the old and new snapshots are decoded and a summary of the changes by path
+// (e.g. .users[2].email: "a@x" → "b@x") is shown above the diff.
+//
+// The [InstaFormatter], [JSONFormatter], [YAMLFormatter] and [TOMLFormatter] are
+// all Decoders.
+type Decoder interface {
+	// Decode decodes a snapshot produced by Format into structured data, that is
+	// a tree of map[string]any, []any and scalar values, as produced by
+	// encoding/json when decoding into an any.
+	Decode(data []byte) (any, error)
+}
+
+// Snapshotter is an interface a type may implement to control how it's snapshotted.
+//
+// Every built-in [Formatter] snapshots the value returned by Snapshot in place of
+// the Snapshotter itself, so a type can present a stable, redacted or simplified
+// view of itself in every format e.g. leaving out a timestamp or a password, or
+// exposing unexported state that would otherwise be lost.
+//
+// Snapshotters nested inside the value being snapshotted are honoured too, except by
+// the [GoFormatter] which only honours a top level Snapshotter.
+type Snapshotter interface {
+	// Snapshot returns the value to snapshot in place of the receiver.
+	Snapshot() any
+}
+
 // InstaFormatter returns a [Formatter] that produces snapshots in the [insta]
 // yaml format.
 //
-// It takes a description for the snapshot.
+// It takes a description for the snapshot. To attach structured context too,
+// use the [Info] option with the default formatter.
 //
 // [insta]: https://crates.io/crates/insta
 func InstaFormatter(description string) Formatter {
-	return insta.NewFormatter(description)
+	return insta.NewFormatter(description, nil)
 }
 
 // TextFormatter returns a [Formatter] that produces snapshots by simply
 // dumping the value as plain text.
+//
+// Strings, numbers and types implementing [encoding.TextMarshaler] or [fmt.Stringer]
+// are written as is. Anything else is pretty printed in a Go-like syntax with one
+// field or element per line, following pointers, sorting map keys and marking
+// cycles, so the snapshot is deterministic from run to run.
 func TextFormatter() Formatter {
 	return text.NewFormatter()
 }
 
+// GoFormatter returns a [Formatter] that produces snapshots as Go source code, a
+// gofmt'd file declaring a variable initialised with a composite literal of the
+// value e.g.
+//
+//	var snapshot = []user.User{
+//		{
+//			Name: "Tom",
+//			Age:  31,
+//		},
+//	}
+//
+// The literal can be copied straight into a test as a fixture. Only exported,
+// non-zero struct fields are included, and values with no literal form (non-nil
+// channels and functions, cyclic values, structs whose state is all unexported
+// or types declared in a _test package) fail the test.
+func GoFormatter() Formatter {
+	return golit.NewFormatter()
+}
+
 // JSONFormatter returns a [Formatter] that produces snapshots by
 // serializing them as JSON documents.
-func JSONFormatter() Formatter {
-	return json.NewFormatter()
+//
+// Values that are already JSON, a [encoding/json.RawMessage] or a string or []byte
+// holding a JSON object or array (e.g. an HTTP response body), are parsed and
+// re-encoded with sorted keys and consistent indentation rather than snapshotted
+// as an opaque string.
+//
+// The layout can be configured by passing a number of [JSONOption].
+func JSONFormatter(options ...JSONOption) Formatter {
+	var config json.Config
+	for _, option := range options {
+		option(&config)
+	}
+
+	return json.NewFormatter(config)
+}
+
+// JSONOption is an option that configures the layout of snapshots produced
+// by the [JSONFormatter].
+type JSONOption func(*json.Config)
+
+// JSONEscapeHTML is a [JSONOption] that controls whether <, > and & in strings
+// are escaped as \u003c, \u003e and \u0026, as they are by default. Pass false
+// to keep HTML readable in snapshots.
+func JSONEscapeHTML(escape bool) JSONOption {
+	return func(c *json.Config) {
+		c.DisableHTMLEscape = !escape
+	}
+}
+
+// JSONIndent is a [JSONOption] that sets the indent for each level of nesting
+// e.g. "\t". An empty indent means the default of two spaces.
+func JSONIndent(indent string) JSONOption {
+	return func(c *json.Config) {
+		c.Indent = indent
+	}
+}
+
+// JSONSortKeys is a [JSONOption] that sorts the keys of every object, including
+// struct fields which by default are written in the order they are declared.
+//
+// Map keys are always sorted. Numbers are preserved exactly, however large or precise.
+func JSONSortKeys() JSONOption {
+	return func(c *json.Config) {
+		c.SortKeys = true
+	}
+}
+
+// JSONTrailingNewline is a [JSONOption] that ends every snapshot with a newline,
+// as most editors expect text files to.
+func JSONTrailingNewline() JSONOption {
+	return func(c *json.Config) {
+		c.TrailingNewline = true
+	}
+}
+
+// XMLFormatter returns a [Formatter] that produces snapshots of XML documents,
+// parsed and written back out canonically so that insignificant changes don't
+// break the snapshot.
+//
+// The document is written one element per line with consistent indentation,
+// attributes sorted by name, runs of whitespace in text collapsed (except within
+// elements with xml:space="preserve") and empty elements self-closing. The value
+// should be the document as a string or []byte, anything else is encoded with
+// [encoding/xml.Marshal] first.
+func XMLFormatter() Formatter {
+	return markup.NewFormatter(markup.Config{})
+}
+
+// HTMLFormatter returns a [Formatter] that produces snapshots of HTML documents,
+// or fragments of them, parsed and written back out canonically like the
+// [XMLFormatter].
+//
+// Void elements are written without a closing slash e.g. <br>, and the contents of
+// pre, textarea, script and style elements are kept exactly as they are. Elements
+// containing text or inline elements like <b> are written on a single line, with
+// the whitespace between their children kept as it changes how they render. The
+// value must be the document as a string or []byte.
+func HTMLFormatter() Formatter {
+	return markup.NewFormatter(markup.Config{HTML: true})
+}
+
+// ANSIFormatter returns a [Formatter] that produces snapshots of terminal output
+// containing ANSI escape sequences, e.g. coloured output from a CLI, with the
+// sequences made readable so changes to colours and styles show up in the diff.
+//
+// Colours and text styles are written as tokens naming the style of the text that
+// follows e.g. "[bold red]error[/]", where "[/]" marks the return to plain text.
+// Any other escape sequence is written as it would be in a quoted Go string e.g.
+// "\x1b[2K". The value must be the text as a string or []byte.
+//
+// Its behaviour may be configured by passing a number of [ANSIOption].
+func ANSIFormatter(options ...ANSIOption) Formatter {
+	var config ansi.Config
+	for _, option := range options {
+		option(&config)
+	}
+
+	return ansi.NewFormatter(config)
+}
+
+// ANSIOption is an option that configures snapshots produced by the [ANSIFormatter].
+type ANSIOption func(*ansi.Config)
+
+// ANSIStrip is an [ANSIOption] that removes escape sequences entirely rather than
+// converting them into tokens, for when only the plain text matters.
+func ANSIStrip() ANSIOption {
+	return func(c *ansi.Config) {
+		c.Strip = true
+	}
+}
+
+// ScreenFormatter returns a [Formatter] that produces snapshots of the screen a
+// terminal would show after the value was written to it, rather than the output
+// itself, for output that redraws itself like progress bars and spinners.
+//
+// Carriage returns, backspaces, tabs, moving the cursor and erasing the line or
+// screen are all emulated, and colours and any other escape sequences ignored.
+// Each row of the screen is written as a line, without trailing spaces or blank
+// rows at the bottom. The value must be the output as a string or []byte.
+//
+// Its behaviour may be configured by passing a number of [ScreenOption].
+func ScreenFormatter(options ...ScreenOption) Formatter {
+	var config screen.Config
+	for _, option := range options {
+		option(&config)
+	}
+
+	return screen.NewFormatter(config)
+}
+
+// ScreenOption is an option that configures snapshots produced by the [ScreenFormatter].
+type ScreenOption func(*screen.Config)
+
+// ScreenSize is a [ScreenOption] that sets the width and height of the emulated
+// terminal, in columns and rows. Text wraps at the right hand edge and scrolls off
+// the top.
+//
+// The default is 80 columns by 24 rows.
+func ScreenSize(width, height int) ScreenOption {
+	return func(c *screen.Config) {
+		c.Width = width
+		c.Height = height
+	}
+}
+
+// GoSourceFormatter returns a [Formatter] that produces snapshots of Go source
+// code, e.g. the output of a code generator, formatted exactly as gofmt would so
+// that changes to formatting alone don't break the snapshot.
+//
+// The value must be the source as a string or []byte, either a whole file or a
+// list of declarations or statements. Source that doesn't parse fails the test,
+// listing each syntax error by line and column.
+func GoSourceFormatter() Formatter {
+	return gosource.NewFormatter()
+}
+
+// HexdumpFormatter returns a [Formatter] that produces snapshots of binary data
+// as an xxd style hex dump, e.g. an encoded message or a generated image.
+//
+// The dump starts with the length in bytes, then a line for every 16 bytes with
+// the offset, the bytes in hex and the bytes as ASCII, so a change to the data
+// shows up in the diff as just the lines it's on. The value must be a []byte (or
+// any other slice of bytes e.g. a [encoding/json.RawMessage]), a string or
+// implement [encoding.BinaryMarshaler].
+func HexdumpFormatter() Formatter {
+	return hexdump.NewFormatter()
+}
+
+// TableFormatter returns a [Formatter] that produces snapshots of slices of
+// structs or maps as a table, one row per element, e.g. the results of a query.
+//
+// For structs the columns are the exported fields, named by their "table" struct
+// tag if they have one (a tag of "-" leaves the field out). For maps the columns
+// are the keys of every row, sorted, so reordering the rows doesn't change the
+// header. A mix of structs and maps has the union of their columns in the order
+// they're first seen. A row without a column has an empty cell.
+//
+// By default the table is aligned text, with one row per line so that the
+// snapshot diffs row by row, this can be configured by passing a number of
+// [TableOption].
+func TableFormatter(options ...TableOption) Formatter {
+	var config table.Config
+	for _, option := range options {
+		option(&config)
+	}
+
+	return table.NewFormatter(config)
+}
+
+// TableOption is an option that configures snapshots produced by the [TableFormatter].
+type TableOption func(*table.Config)
+
+// TableCSV is a [TableOption] that writes tables as CSV (to .snap.csv files)
+// rather than aligned text.
+func TableCSV() TableOption {
+	return func(c *table.Config) {
+		c.CSV = true
+	}
+}
+
+// TableTag is a [TableOption] that sets the struct tag naming columns, e.g. "json"
+// to reuse existing json tags.
+//
+// The default is "table".
+func TableTag(key string) TableOption {
+	return func(c *table.Config) {
+		c.Tag = key
+	}
+}
+
+// TOMLFormatter returns a [Formatter] that produces snapshots by
+// serializing them as TOML documents.
+//
+// A TOML document is a table, so the value being snapshotted must be a struct or
+// a map. Map keys are sorted and nested tables and arrays of tables are written
+// after the plain keys of the table they're in, so the output is deterministic.
+func TOMLFormatter() Formatter {
+	return toml.NewFormatter()
 }
 
 // YAMLFormatter returns a [Formatter] that produces snapshots by
 // serializing them as YAML documents.
-func YAMLFormatter() Formatter {
-	return yaml.NewFormatter()
+//
+// Multi-line strings are written as literal block scalars (|) so they diff line
+// by line, the rest of the layout can be configured by passing a number of
+// [YAMLOption].
+//
+// Text is snapshotted as a string unless the [YAMLParseText] option is passed.
+func YAMLFormatter(options ...YAMLOption) Formatter {
+	var config yaml.Config
+	for _, option := range options {
+		option(&config)
+	}
+
+	return yaml.NewFormatter(config)
+}
+
+// YAMLOption is an option that configures the layout of snapshots produced
+// by the [YAMLFormatter].
+type YAMLOption func(*yaml.Config)
+
+// YAMLSortKeys is a [YAMLOption] that sorts the keys of every mapping, including
+// struct fields which by default are written in the order they are declared.
+//
+// Map keys are always sorted.
+func YAMLSortKeys() YAMLOption {
+	return func(c *yaml.Config) {
+		c.SortKeys = true
+	}
+}
+
+// YAMLFlowSequences is a [YAMLOption] that writes sequences of n or fewer single line
+// scalars in flow style e.g. [1, 2, 3], rather than one element per line.
+//
+// By default sequences are always written one element per line.
+func YAMLFlowSequences(n int) YAMLOption {
+	return func(c *yaml.Config) {
+		c.FlowSequences = max(n, 0)
+	}
+}
+
+// YAMLParseText is a [YAMLOption] that parses multi-line YAML text whose root is a
+// mapping or sequence and re-encodes it canonically, like the [JSONFormatter] does
+// for JSON text, rather than snapshotting it as a string.
+//
+// It's off by default as plenty of ordinary text, CLI help for instance, happens
+// to be valid YAML and would be mangled.
+func YAMLParseText() YAMLOption {
+	return func(c *yaml.Config) {
+		c.ParseText = true
+	}
+}
+
+// YAMLLineWidth is a [YAMLOption] that sets the preferred line width, long strings
+// are folded onto multiple lines to fit within it. A width of 0 or less means
+// strings are never folded.
+//
+// The default is 80.
+func YAMLLineWidth(width int) YAMLOption {
+	return func(c *yaml.Config) {
+		c.LineWidth = width
+		if width <= 0 {
+			c.LineWidth = -1
+		}
+	}
 }
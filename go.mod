@@ -3,13 +3,14 @@ module go.followtheprocess.codes/snapshot
 go 1.26
 
 require (
+	github.com/BurntSushi/toml v1.6.0
 	go.followtheprocess.codes/diff v0.2.0
 	go.followtheprocess.codes/hue v1.2.0
 	go.followtheprocess.codes/test v1.4.0
 	go.yaml.in/yaml/v4 v4.0.0-rc.6
+	golang.org/x/net v0.56.0
+	golang.org/x/term v0.44.0
+	golang.org/x/text v0.38.0
 )
 
-require (
-	golang.org/x/sys v0.46.0 // indirect
-	golang.org/x/term v0.44.0 // indirect
-)
+require golang.org/x/sys v0.46.0 // indirect
@@ -0,0 +1,198 @@
+// Package compare provides ways of deciding whether a new snapshot matches the
+// one previously saved.
+package compare
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"reflect"
+	"slices"
+
+	"go.yaml.in/yaml/v4"
+)
+
+// Exact implements [snapshot.Comparer] and requires snapshots to be byte for
+// byte identical.
+type Exact struct{}
+
+// Equal reports whether the old and current snapshots are identical.
+func (e Exact) Equal(old, current []byte) (bool, error) {
+	return bytes.Equal(old, current), nil
+}
+
+// Whitespace implements [snapshot.Comparer] and treats snapshots as equal if they
+// differ only in whitespace.
+//
+// Any run of whitespace (including newlines) is equivalent to any other, and
+// leading and trailing whitespace is ignored entirely.
+type Whitespace struct{}
+
+// Equal reports whether the old and current snapshots are equal, ignoring
+// differences in whitespace.
+func (w Whitespace) Equal(old, current []byte) (bool, error) {
+	return slices.EqualFunc(bytes.Fields(old), bytes.Fields(current), bytes.Equal), nil
+}
+
+// JSON implements [snapshot.Comparer] and treats snapshots as equal if they
+// encode the same JSON value, regardless of key order, whitespace or how
+// numbers are written.
+type JSON struct{}
+
+// Equal reports whether the old and current snapshots are semantically equal
+// JSON documents.
+func (j JSON) Equal(old, current []byte) (bool, error) {
+	before, err := decodeJSON(old)
+	if err != nil {
+		return false, fmt.Errorf("could not decode old snapshot as JSON: %w", err)
+	}
+
+	after, err := decodeJSON(current)
+	if err != nil {
+		return false, fmt.Errorf("could not decode new snapshot as JSON: %w", err)
+	}
+
+	return equal(before, after), nil
+}
+
+// YAML implements [snapshot.Comparer] and treats snapshots as equal if they
+// encode the same YAML documents, regardless of key order, formatting or
+// scalar style.
+//
+// Snapshots containing multiple documents, such as those in the insta format,
+// are equal if all their documents are.
+type YAML struct{}
+
+// Equal reports whether the old and current snapshots are semantically equal
+// YAML documents.
+func (y YAML) Equal(old, current []byte) (bool, error) {
+	before, err := decodeYAML(old)
+	if err != nil {
+		return false, fmt.Errorf("could not decode old snapshot as YAML: %w", err)
+	}
+
+	after, err := decodeYAML(current)
+	if err != nil {
+		return false, fmt.Errorf("could not decode new snapshot as YAML: %w", err)
+	}
+
+	return equal(before, after), nil
+}
+
+// equal reports whether two values decoded from JSON or YAML are semantically equal.
+//
+// Maps are equal if they have the same keys with equal values regardless of
+// order, sequences if they have equal elements in the same order, and numbers
+// if they have the same numeric value regardless of how they were written (so
+// 1, 1.0 and 1e0 are all equal).
+func equal(a, b any) bool {
+	if x, ok := number(a); ok {
+		y, ok := number(b)
+
+		return ok && x.Cmp(y) == 0
+	}
+
+	switch a := a.(type) {
+	case map[string]any:
+		return mapsEqual(a, b)
+	case map[any]any:
+		return mapsEqual(a, b)
+	case []any:
+		b, ok := b.([]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+
+		for i := range a {
+			if !equal(a[i], b[i]) {
+				return false
+			}
+		}
+
+		return true
+	default:
+		return reflect.DeepEqual(a, b)
+	}
+}
+
+// mapsEqual reports whether the map a is semantically equal to b.
+func mapsEqual[K comparable](a map[K]any, b any) bool {
+	other, ok := b.(map[K]any)
+	if !ok || len(a) != len(other) {
+		return false
+	}
+
+	for key, value := range a {
+		otherValue, ok := other[key]
+		if !ok || !equal(value, otherValue) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// number returns value as an exact rational number, reporting whether it
+// was a number at all.
+func number(value any) (*big.Rat, bool) {
+	switch value := value.(type) {
+	case json.Number:
+		return new(big.Rat).SetString(value.String())
+	case int:
+		return new(big.Rat).SetInt64(int64(value)), true
+	case int64:
+		return new(big.Rat).SetInt64(value), true
+	case uint64:
+		return new(big.Rat).SetUint64(value), true
+	case float64:
+		// NaN and ±Inf have no rational representation, SetFloat64 returns nil
+		r := new(big.Rat).SetFloat64(value)
+
+		return r, r != nil
+	default:
+		return nil, false
+	}
+}
+
+// decodeJSON decodes a single JSON document, preserving numbers exactly.
+func decodeJSON(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	// Anything after the document means it wasn't a single JSON document
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("unexpected content after JSON document")
+	}
+
+	return value, nil
+}
+
+// decodeYAML decodes all the YAML documents in data.
+func decodeYAML(data []byte) ([]any, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+
+	var documents []any
+
+	for {
+		var document any
+
+		err := decoder.Decode(&document)
+		if errors.Is(err, io.EOF) {
+			return documents, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		documents = append(documents, document)
+	}
+}
@@ -0,0 +1,131 @@
+package compare_test
+
+import (
+	"testing"
+
+	"go.followtheprocess.codes/snapshot/internal/compare"
+	"go.followtheprocess.codes/test"
+)
+
+// comparer mirrors snapshot.Comparer.
+type comparer interface {
+	Equal(old, current []byte) (bool, error)
+}
+
+func TestComparers(t *testing.T) {
+	tests := []struct {
+		comparer comparer // The comparer under test
+		name     string   // Name of the test case
+		old      string   // Old snapshot
+		current  string   // New snapshot
+		want     bool     // Whether the snapshots should be equal
+		wantErr  bool     // Whether we want an error
+	}{
+		{
+			name:     "exact equal",
+			comparer: compare.Exact{},
+			old:      "hello\n",
+			current:  "hello\n",
+			want:     true,
+		},
+		{
+			name:     "exact whitespace",
+			comparer: compare.Exact{},
+			old:      "hello\n",
+			current:  "hello \n",
+			want:     false,
+		},
+		{
+			name:     "whitespace equal",
+			comparer: compare.Whitespace{},
+			old:      "  hello\tthere\n\nworld",
+			current:  "hello there\nworld\n",
+			want:     true,
+		},
+		{
+			name:     "whitespace different",
+			comparer: compare.Whitespace{},
+			old:      "hello there",
+			current:  "hellothere",
+			want:     false,
+		},
+		{
+			name:     "json key order and formatting",
+			comparer: compare.JSON{},
+			old:      `{"b": [1, 2], "a": {"c": null}}`,
+			current:  "{\n  \"a\": {\n    \"c\": null\n  },\n  \"b\": [\n    1,\n    2\n  ]\n}\n",
+			want:     true,
+		},
+		{
+			name:     "json numbers",
+			comparer: compare.JSON{},
+			old:      `{"n": 1.0, "big": 12345678901234567890}`,
+			current:  `{"n": 1e0, "big": 12345678901234567890}`,
+			want:     true,
+		},
+		{
+			name:     "json big numbers differ",
+			comparer: compare.JSON{},
+			old:      `12345678901234567890`,
+			current:  `12345678901234567891`,
+			want:     false,
+		},
+		{
+			name:     "json element order matters",
+			comparer: compare.JSON{},
+			old:      `[1, 2]`,
+			current:  `[2, 1]`,
+			want:     false,
+		},
+		{
+			name:     "json invalid",
+			comparer: compare.JSON{},
+			old:      `{"a": 1}`,
+			current:  `{"a": `,
+			wantErr:  true,
+		},
+		{
+			name:     "json trailing content",
+			comparer: compare.JSON{},
+			old:      `{"a": 1}`,
+			current:  `{"a": 1} {"b": 2}`,
+			wantErr:  true,
+		},
+		{
+			name:     "yaml key order and style",
+			comparer: compare.YAML{},
+			old:      "b: [1, 2]\na: 'text'\n",
+			current:  "a: text\nb:\n  - 1\n  - 2\n",
+			want:     true,
+		},
+		{
+			name:     "yaml multiple documents",
+			comparer: compare.YAML{},
+			old:      "source: a_test.go\n---\nvalue: 1\n",
+			current:  "source: a_test.go\n---\nvalue: 1.0\n",
+			want:     true,
+		},
+		{
+			name:     "yaml different documents",
+			comparer: compare.YAML{},
+			old:      "source: a_test.go\n---\nvalue: 1\n",
+			current:  "source: b_test.go\n---\nvalue: 1\n",
+			want:     false,
+		},
+		{
+			name:     "yaml invalid",
+			comparer: compare.YAML{},
+			old:      "key: [unclosed",
+			current:  "key: value",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.comparer.Equal([]byte(tt.old), []byte(tt.current))
+			test.WantErr(t, err, tt.wantErr)
+			test.Equal(t, got, tt.want)
+		})
+	}
+}
@@ -0,0 +1,326 @@
+// Package ansi provides a formatter for snapshots of terminal output containing
+// ANSI escape sequences, writing colours and text styles as readable tokens so
+// changes to them show up in a diff.
+package ansi
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go.followtheprocess.codes/snapshot/internal/format"
+)
+
+// colours are the names of the 8 standard colours, in the order of their codes.
+var colours = [...]string{"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white"} //nolint:gochecknoglobals // Effectively a constant
+
+// attributes are the names of the text attributes, in the order they're written.
+var attributes = [...]string{"bold", "dim", "italic", "underline", "blink", "reverse", "hidden", "strikethrough"} //nolint:gochecknoglobals // Effectively a constant
+
+// underlines are the names of the underline styles, in the order of their codes
+// e.g. 4:3 is a curly underline, "" is a single underline.
+var underlines = [...]string{"", "", "double", "curly", "dotted", "dashed"} //nolint:gochecknoglobals // Effectively a constant
+
+// underline is the index in attributes of underline.
+const underline = 3
+
+// Config controls how escape sequences are written, the zero value converts them
+// into tokens.
+type Config struct {
+	Strip bool // Remove escape sequences entirely, leaving only the plain text
+}
+
+// Formatter implements [snapshot.Formatter] and returns a snapshot format for
+// text containing ANSI escape sequences.
+type Formatter struct {
+	config Config
+}
+
+// NewFormatter returns a new ANSI Formatter, writing escape sequences according
+// to config.
+func NewFormatter(config Config) Formatter {
+	return Formatter{config: config}
+}
+
+// Ext returns the file extension for an ANSI snapshot.
+func (f Formatter) Ext() string {
+	return ".snap.txt"
+}
+
+// Format returns a snapshot of the value, which must be text as a string or []byte,
+// with its ANSI escape sequences made visible.
+//
+// SGR sequences, which set the colour and style of the text, are converted into
+// tokens naming the style of the text that follows, e.g. "[bold red]error[/]"
+// where "[/]" marks the return to plain text. Background colours are prefixed with
+// "on", bright colours with "bright-", 256 colours are written as "color(n)" and
+// 24 bit colours as "#rrggbb". Underline styles other than a single line are
+// written as e.g. "curly-underline" and underline colours as e.g. "underline-#ff0000".
+// Parameters may be separated by semicolons or colons, e.g. 38;5;196 or 38:5:196.
+// Any other escape sequence, e.g. moving the cursor, is written as it would be in a
+// quoted Go string e.g. "\x1b[2K".
+//
+// If the config says to strip them, every escape sequence is removed instead.
+func (f Formatter) Format(value any) ([]byte, error) {
+	var text []byte
+
+	switch value := format.Snapshot(value).(type) {
+	case string:
+		text = []byte(value)
+	case []byte:
+		text = value
+	default:
+		return nil, fmt.Errorf("an ANSI snapshot must be a string or []byte, got %T", value)
+	}
+
+	w := writer{buf: &bytes.Buffer{}, strip: f.config.Strip}
+
+	for len(text) > 0 {
+		escape, ok := format.ParseEscape(text)
+
+		switch {
+		case !ok:
+			w.text(text[0])
+
+			escape.Len = 1
+		case escape.Intro == '[' && escape.Final == 'm':
+			w.current.apply(escape.Params)
+		case !f.config.Strip:
+			quoted := strconv.Quote(string(text[:escape.Len]))
+			w.buf.WriteString(quoted[1 : len(quoted)-1])
+		}
+
+		text = text[escape.Len:]
+	}
+
+	w.close()
+
+	return w.buf.Bytes(), nil
+}
+
+// writer writes text, with tokens for the style of each run of it.
+type writer struct {
+	buf     *bytes.Buffer
+	current style // The style set by the sequences so far
+	written style // The style of the last text written
+	strip   bool  // Write no tokens at all
+}
+
+// text writes a byte of text, preceded by a token if its style has changed since
+// the last one.
+//
+// Writing tokens only when there's text to apply them to means a run of sequences
+// produces a single token, and sequences with nothing after them none at all.
+func (w *writer) text(b byte) {
+	if !w.strip && w.current != w.written {
+		w.close()
+
+		if !w.current.plain() {
+			fmt.Fprintf(w.buf, "[%s]", w.current)
+		}
+
+		w.written = w.current
+	}
+
+	w.buf.WriteByte(b)
+}
+
+// close writes the token returning to plain text, if the last text written wasn't.
+func (w *writer) close() {
+	if !w.written.plain() {
+		w.buf.WriteString("[/]")
+		w.written = style{}
+	}
+}
+
+// style is the colour and attributes of text.
+type style struct {
+	foreground string // Foreground colour, "" for the default
+	background string // Background colour, "" for the default
+	decoration string // Underline colour, "" for the default
+	underline  string // Underline style if underlined, "" for a single line
+	attributes uint   // Set of attributes, bit i is attributes[i]
+}
+
+// plain reports whether s is unstyled.
+func (s style) plain() bool {
+	return s == style{}
+}
+
+// String returns the token contents for s e.g. "bold red on blue".
+func (s style) String() string {
+	var words []string
+
+	for i, attribute := range attributes {
+		if s.attributes&(1<<i) == 0 {
+			continue
+		}
+
+		if i == underline && s.underline != "" {
+			attribute = s.underline + "-" + attribute
+		}
+
+		words = append(words, attribute)
+	}
+
+	if s.foreground != "" {
+		words = append(words, s.foreground)
+	}
+
+	if s.background != "" {
+		words = append(words, "on", s.background)
+	}
+
+	if s.decoration != "" {
+		words = append(words, "underline-"+s.decoration)
+	}
+
+	return strings.Join(words, " ")
+}
+
+// apply updates s with the parameters of an SGR sequence e.g. "1;31", ignoring any
+// it doesn't recognise.
+func (s *style) apply(params string) {
+	// No parameters at all is a reset
+	if params == "" {
+		*s = style{}
+
+		return
+	}
+
+	codes := strings.Split(params, ";")
+	for i := 0; i < len(codes); i++ {
+		// A parameter may have colon separated sub-parameters e.g. 38:5:196, which
+		// are its own, unlike those that follow it separated by semicolons
+		subs := strings.Split(codes[i], ":")
+
+		code, err := strconv.Atoi(subs[0])
+		if err != nil && subs[0] != "" {
+			continue
+		}
+
+		switch {
+		case len(subs) > 1 && code == 4:
+			if n, err := strconv.Atoi(subs[1]); err == nil {
+				s.underlined(n)
+			}
+		case len(subs) > 1 && (code == 38 || code == 48 || code == 58):
+			// The 24 bit form may have a colour space first e.g. 38:2::255:0:0
+			if subs[1] == "2" && len(subs) > 5 {
+				subs = slices.Delete(subs, 2, 3)
+			}
+
+			colour, _ := extended(subs[1:])
+			s.colour(code, colour)
+		case len(subs) > 1:
+			// No other parameters have sub-parameters
+		case code == 0:
+			*s = style{}
+		case code == 4:
+			s.underlined(1)
+		case code == 21: // Double underline
+			s.underlined(2)
+		case code >= 1 && code <= 9 && code != 6:
+			s.attributes |= 1 << attribute(code)
+		case code == 22: // Neither bold nor dim
+			s.attributes &^= 1<<attribute(1) | 1<<attribute(2)
+		case code == 24:
+			s.underlined(0)
+		case code >= 23 && code <= 29 && code != 26:
+			s.attributes &^= 1 << attribute(code-20)
+		case code >= 30 && code <= 37:
+			s.foreground = colours[code-30]
+		case code >= 40 && code <= 47:
+			s.background = colours[code-40]
+		case code >= 90 && code <= 97:
+			s.foreground = "bright-" + colours[code-90]
+		case code >= 100 && code <= 107:
+			s.background = "bright-" + colours[code-100]
+		case code == 39 || code == 49 || code == 59:
+			s.colour(code-1, "")
+		case code == 38 || code == 48 || code == 58:
+			colour, used := extended(codes[i+1:])
+			i += used
+
+			s.colour(code, colour)
+		}
+	}
+}
+
+// colour sets the colour set by code, 38 for the foreground, 48 for the background
+// and 58 for underlines.
+func (s *style) colour(code int, colour string) {
+	switch code {
+	case 38:
+		s.foreground = colour
+	case 48:
+		s.background = colour
+	case 58:
+		s.decoration = colour
+	}
+}
+
+// underlined sets the underline style n, as in a 4:n code, 0 for none and 1 for
+// a single line, ignoring any it doesn't recognise.
+func (s *style) underlined(n int) {
+	if n < 0 || n >= len(underlines) {
+		return
+	}
+
+	if n == 0 {
+		s.attributes &^= 1 << underline
+		s.underline = ""
+
+		return
+	}
+
+	s.attributes |= 1 << underline
+	s.underline = underlines[n]
+}
+
+// attribute returns the index in attributes of the attribute set by code.
+func attribute(code int) int {
+	// There's no attribute 6, rapid blink isn't distinguished from blink
+	if code > 6 {
+		return code - 2
+	}
+
+	return code - 1
+}
+
+// extended returns the colour described by the parameters following a 38 or 48
+// code and how many of them it used, or "" if they don't describe one.
+func extended(codes []string) (string, int) {
+	if len(codes) == 0 {
+		return "", 0
+	}
+
+	switch codes[0] {
+	case "5":
+		if len(codes) < 2 {
+			return "", len(codes)
+		}
+
+		return "color(" + codes[1] + ")", 2
+	case "2":
+		if len(codes) < 4 {
+			return "", len(codes)
+		}
+
+		rgb := "#"
+		for _, code := range codes[1:4] {
+			n, err := strconv.ParseUint(code, 10, 8)
+			if err != nil {
+				return "", 4
+			}
+
+			rgb += fmt.Sprintf("%02x", n)
+		}
+
+		return rgb, 4
+	default:
+		return "", 1
+	}
+}
@@ -0,0 +1,79 @@
+package ansi_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.followtheprocess.codes/hue"
+	"go.followtheprocess.codes/snapshot/internal/format/ansi"
+	"go.followtheprocess.codes/test"
+)
+
+func TestFormatter(t *testing.T) {
+	hue.Enabled(true)
+	t.Cleanup(func() { hue.Enabled(false) })
+
+	tests := []struct {
+		value  any
+		name   string
+		config ansi.Config
+	}{
+		{
+			name:  "plain",
+			value: "no escapes here\n",
+		},
+		{
+			name:  "styles",
+			value: "\x1b[1;31mError:\x1b[0m something \x1b[32mpassed\x1b[0m\n\x1b[1m\x1b[4mboth\x1b[22m underlined\x1b[0m\n",
+		},
+		{
+			name:  "extended",
+			value: []byte("\x1b[38;5;208morange\x1b[39m \x1b[48;2;255;0;128;97mpink\x1b[m\n"),
+		},
+		{
+			// Sub-parameters separated by colons, as kitty, wezterm and others write them
+			name:  "sub_parameters",
+			value: "\x1b[38:5:196mred\x1b[48:2::0:0:255m on blue\x1b[38:2:255:128:0m orange\x1b[m\n\x1b[4:3;58:2::255:0:0mcurly\x1b[4:0m \x1b[21mdouble\x1b[24;59m none\x1b[0m\n",
+		},
+		{
+			name:  "hue",
+			value: (hue.Bold | hue.Cyan).Text("info") + " " + hue.BrightBlackBackground.Text("grey") + "\n",
+		},
+		{
+			name:  "other_sequences",
+			value: "\x1b[2Kdone\x1b[1A \x1b]8;;https://example.com\x07link\x1b]8;;\x07\n",
+		},
+		{
+			// As written by tput sgr0, selecting the character set before resetting
+			name:  "charset",
+			value: "\x1b[1;31mError:\x1b(B\x1b[m something\n",
+		},
+		{
+			name:   "strip",
+			value:  "\x1b[1;31mError:\x1b(B\x1b[m \x1b[2Kdone \x1b]8;;https://example.com\x07link\x1b]8;;\x07\n",
+			config: ansi.Config{Strip: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test.ColorEnabled(os.Getenv("CI") == "")
+
+			path := filepath.Join("testdata", "TestFormatter", tt.name+".snap.txt")
+
+			want, err := os.ReadFile(path)
+			test.Ok(t, err)
+
+			got, err := ansi.NewFormatter(tt.config).Format(tt.value)
+			test.Ok(t, err)
+
+			test.DiffBytes(t, got, want)
+		})
+	}
+}
+
+func TestFormatterError(t *testing.T) {
+	_, err := ansi.NewFormatter(ansi.Config{}).Format(42)
+	test.Err(t, err)
+}
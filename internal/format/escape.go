@@ -0,0 +1,77 @@
+package format
+
+// esc is the escape character that starts every ANSI escape sequence.
+const esc = '\x1b'
+
+// Escape is an ANSI escape sequence, as written to a terminal.
+type Escape struct {
+	Params string // Parameters of a control sequence e.g. "1;31" in ESC [ 1 ; 3 1 m
+	Len    int    // Length of the whole sequence in bytes
+	Intro  byte   // The byte after the escape, '[' for a control sequence, ']' for an operating system command
+	Final  byte   // Final byte of a control sequence e.g. 'm', 0 for any other sequence or if it's incomplete
+}
+
+// ParseEscape returns the escape sequence at the start of text, reporting whether
+// there is one.
+//
+// Control sequences (CSI) are parameter bytes, then intermediate bytes, then a
+// final byte. Operating system commands (OSC) e.g. hyperlinks or setting the
+// window title, run until a BEL or the string terminator ESC \. Any other escape
+// is any intermediate bytes and then a final byte, e.g. ESC ( B which selects the
+// character set and is written by tput sgr0, or just the final byte e.g. ESC 7.
+// An incomplete sequence at the very end of text runs to the end of it.
+func ParseEscape(text []byte) (Escape, bool) {
+	if len(text) == 0 || text[0] != esc {
+		return Escape{}, false
+	}
+
+	if len(text) == 1 {
+		return Escape{Len: 1}, true
+	}
+
+	e := Escape{Intro: text[1]}
+
+	switch e.Intro {
+	case '[':
+		i := 2
+		for i < len(text) && text[i] >= 0x20 && text[i] <= 0x3f {
+			i++
+		}
+
+		e.Params = string(text[2:i])
+
+		if i == len(text) {
+			e.Len = i
+
+			return e, true
+		}
+
+		e.Final = text[i]
+		e.Len = i + 1
+	case ']':
+		e.Len = len(text)
+
+		for i := 2; i < len(text); i++ {
+			if text[i] == '\a' {
+				e.Len = i + 1
+
+				break
+			}
+
+			if text[i] == esc && i+1 < len(text) && text[i+1] == '\\' {
+				e.Len = i + 2
+
+				break
+			}
+		}
+	default:
+		i := 1
+		for i < len(text) && text[i] >= 0x20 && text[i] <= 0x2f {
+			i++
+		}
+
+		e.Len = min(i+1, len(text))
+	}
+
+	return e, true
+}
@@ -0,0 +1,96 @@
+package format_test
+
+import (
+	"testing"
+
+	"go.followtheprocess.codes/snapshot/internal/format"
+	"go.followtheprocess.codes/test"
+)
+
+func TestParseEscape(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want format.Escape
+		ok   bool
+	}{
+		{
+			name: "text",
+			text: "plain",
+			ok:   false,
+		},
+		{
+			name: "empty",
+			text: "",
+			ok:   false,
+		},
+		{
+			name: "sgr",
+			text: "\x1b[1;31mred",
+			want: format.Escape{Intro: '[', Params: "1;31", Final: 'm', Len: 7},
+			ok:   true,
+		},
+		{
+			name: "private",
+			text: "\x1b[?25l",
+			want: format.Escape{Intro: '[', Params: "?25", Final: 'l', Len: 6},
+			ok:   true,
+		},
+		{
+			name: "incomplete csi",
+			text: "\x1b[1;3",
+			want: format.Escape{Intro: '[', Params: "1;3", Len: 5},
+			ok:   true,
+		},
+		{
+			name: "osc bel",
+			text: "\x1b]0;title\x07after",
+			want: format.Escape{Intro: ']', Len: 10},
+			ok:   true,
+		},
+		{
+			name: "osc string terminator",
+			text: "\x1b]8;;url\x1b\\link",
+			want: format.Escape{Intro: ']', Len: 10},
+			ok:   true,
+		},
+		{
+			name: "unterminated osc",
+			text: "\x1b]0;title",
+			want: format.Escape{Intro: ']', Len: 9},
+			ok:   true,
+		},
+		{
+			name: "two byte",
+			text: "\x1b7saved",
+			want: format.Escape{Intro: '7', Len: 2},
+			ok:   true,
+		},
+		{
+			name: "intermediate",
+			text: "\x1b(B\x1b[m",
+			want: format.Escape{Intro: '(', Len: 3},
+			ok:   true,
+		},
+		{
+			name: "incomplete intermediate",
+			text: "\x1b(",
+			want: format.Escape{Intro: '(', Len: 2},
+			ok:   true,
+		},
+		{
+			name: "lone escape",
+			text: "\x1b",
+			want: format.Escape{Len: 1},
+			ok:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := format.ParseEscape([]byte(tt.text))
+			test.Equal(t, ok, tt.ok)
+			test.Equal(t, got, tt.want)
+		})
+	}
+}
@@ -0,0 +1,45 @@
+// Package formattest provides values shared by the tests of the formatters.
+package formattest
+
+import "time"
+
+// Session is a [format.Snapshotter] that redacts its token.
+type Session struct {
+	User  string
+	Token string
+}
+
+// Snapshot returns the session with its token redacted.
+func (s Session) Snapshot() any {
+	return map[string]string{"user": s.User, "token": "<redacted>"}
+}
+
+// Level is a named type whose underlying type is a basic one.
+type Level int
+
+// User is a struct with fields of most kinds, including an unexported one.
+type User struct {
+	Joined   time.Time
+	Manager  *User
+	Labels   map[string]string
+	Name     string
+	Nickname *string
+	password string
+	Friends  []string
+	Scores   []float64
+	Age      int
+	Level    Level
+	Admin    bool
+}
+
+// WithPassword returns a copy of the user with its unexported password set.
+func (u User) WithPassword(password string) User {
+	u.password = password
+
+	return u
+}
+
+// Box is a generic type, whose name includes its type argument.
+type Box[T any] struct {
+	V T
+}
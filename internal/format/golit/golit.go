@@ -0,0 +1,721 @@
+// Package golit provides a formatter for snapshots as Go source code.
+//
+// A snapshot is a gofmt'd Go file declaring a single variable, initialised
+// with a composite literal of the snapshot value e.g.
+//
+//	package snapshots
+//
+//	import "example.com/app/user"
+//
+//	var snapshot = []user.User{
+//		{
+//			Name: "Tom",
+//			Age:  31,
+//		},
+//	}
+//
+// So the literal can be copied straight into a test as a fixture, and the
+// snapshot itself can be type checked.
+package golit
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"go/format"
+	"maps"
+	"math"
+	"path"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	snapformat "go.followtheprocess.codes/snapshot/internal/format"
+)
+
+// Formatter implements [snapshot.Formatter] and returns a Go source
+// snapshot format.
+type Formatter struct{}
+
+// NewFormatter returns a new Go Formatter.
+func NewFormatter() Formatter {
+	return Formatter{}
+}
+
+// Ext returns the file extension for a Go snapshot.
+func (f Formatter) Ext() string {
+	return ".snap.go"
+}
+
+// Format returns a Go source snapshot of the value.
+//
+// Only exported, non-zero struct fields are written, as those are all a
+// composite literal outside the struct's package can set. Values that have no
+// literal form, such as non-nil channels and functions, cycles, structs whose
+// state is all unexported and types declared in _test packages, are an error.
+//
+// If the value is a [snapformat.Snapshotter] its snapshot is written instead.
+// Nested Snapshotters aren't, their snapshot would rarely be assignable to the
+// field or element they're in.
+func (f Formatter) Format(value any) ([]byte, error) {
+	value = snapformat.Snapshot(value)
+
+	g := generator{
+		imports:  make(map[string]string),
+		names:    make(map[string]bool),
+		visiting: make(map[visit]bool),
+	}
+
+	expr, err := g.value(reflect.ValueOf(value), nil, false)
+	if err == nil {
+		err = g.err
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	src := &bytes.Buffer{}
+	src.WriteString("package snapshots\n\n")
+
+	g.writeImports(src)
+
+	if value == nil {
+		// An untyped nil needs a type to be declared
+		expr = "any(nil)"
+	}
+
+	fmt.Fprintf(src, "var snapshot = %s\n", expr)
+
+	formatted, err := format.Source(src.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("could not format Go snapshot: %w", err)
+	}
+
+	return formatted, nil
+}
+
+// writeImports writes the import declaration for every package referenced, standard
+// library first, the way goimports would.
+func (g *generator) writeImports(src *bytes.Buffer) {
+	var std, other []string
+
+	for _, importPath := range slices.Sorted(maps.Keys(g.imports)) {
+		spec := strconv.Quote(importPath)
+		if name := g.imports[importPath]; name != path.Base(importPath) {
+			spec = name + " " + spec
+		}
+
+		first, _, _ := strings.Cut(importPath, "/")
+		if strings.Contains(first, ".") {
+			other = append(other, spec)
+		} else {
+			std = append(std, spec)
+		}
+	}
+
+	switch specs := slices.Concat(std, other); len(specs) {
+	case 0:
+		return
+	case 1:
+		fmt.Fprintf(src, "import %s\n\n", specs[0])
+	default:
+		src.WriteString("import (\n")
+
+		for i, spec := range specs {
+			if i == len(std) && i > 0 {
+				src.WriteByte('\n')
+			}
+
+			fmt.Fprintf(src, "\t%s\n", spec)
+		}
+
+		src.WriteString(")\n\n")
+	}
+}
+
+// visit identifies a pointer, map or slice being generated, to detect cycles.
+type visit struct {
+	typ reflect.Type
+	ptr uintptr
+}
+
+// generator generates Go source for values.
+type generator struct {
+	imports  map[string]string // Import path to package name for every package referenced
+	names    map[string]bool   // Package names in use, to alias any that clash
+	visiting map[visit]bool    // Pointers, maps and slices on the current path
+	err      error             // The first package referenced that can't be imported
+}
+
+// value returns the Go expression for v, where an expression of type static is
+// expected (nil if there is no expected type e.g. the top level).
+//
+// If elide is true, v is an element of a composite literal whose type may be
+// omitted, as in []T{{...}} rather than []T{T{...}}.
+func (g *generator) value(v reflect.Value, static reflect.Type, elide bool) (string, error) {
+	if !v.IsValid() {
+		return "nil", nil
+	}
+
+	typ := v.Type()
+
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return g.null(typ, static), nil
+		}
+
+		return g.value(v.Elem(), typ, false)
+	case reflect.Pointer:
+		return g.pointer(v, static, elide)
+	case reflect.Struct:
+		if t, ok := v.Interface().(time.Time); ok {
+			return g.time(t), nil
+		}
+
+		return g.structure(v, elide)
+	case reflect.Slice, reflect.Array:
+		return g.sequence(v, static, elide)
+	case reflect.Map:
+		return g.mapping(v, static, elide)
+	case reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		if v.IsNil() {
+			return g.null(typ, static), nil
+		}
+
+		return "", fmt.Errorf("cannot represent a non-nil %s as a Go literal", typ)
+	default:
+		return g.scalar(v, static), nil
+	}
+}
+
+// scalar returns the Go expression for a boolean, number or string.
+func (g *generator) scalar(v reflect.Value, static reflect.Type) string {
+	var literal string
+
+	// The type an untyped constant of this kind defaults to, if v is of that type
+	// and that's acceptable where it's going, no conversion is needed
+	var untyped reflect.Type
+
+	switch v.Kind() {
+	case reflect.Bool:
+		literal, untyped = strconv.FormatBool(v.Bool()), reflect.TypeFor[bool]()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		literal, untyped = strconv.FormatInt(v.Int(), 10), reflect.TypeFor[int]()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		literal = strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		literal, untyped = g.float(v.Float(), v.Type().Bits()), reflect.TypeFor[float64]()
+	case reflect.Complex64, reflect.Complex128:
+		literal, untyped = strconv.FormatComplex(v.Complex(), 'g', -1, v.Type().Bits()), reflect.TypeFor[complex128]()
+	default:
+		literal, untyped = str(v.String()), reflect.TypeFor[string]()
+	}
+
+	typ := v.Type()
+
+	switch {
+	case typ == static:
+		return literal
+	case typ == untyped && (static == nil || static.Kind() == reflect.Interface):
+		return literal
+	default:
+		return g.typeName(typ) + "(" + literal + ")"
+	}
+}
+
+// float returns the Go expression for a float, always with a decimal point or
+// exponent so that it's a float constant.
+func (g *generator) float(f float64, bits int) string {
+	switch {
+	case math.IsNaN(f):
+		return g.use("math", "math") + ".NaN()"
+	case math.IsInf(f, 1):
+		return g.use("math", "math") + ".Inf(1)"
+	case math.IsInf(f, -1):
+		return g.use("math", "math") + ".Inf(-1)"
+	}
+
+	formatted := strconv.FormatFloat(f, 'g', -1, bits)
+	if !strings.ContainsAny(formatted, ".e") {
+		formatted += ".0"
+	}
+
+	return formatted
+}
+
+// str returns the Go string literal for s, a raw string if it spans multiple
+// lines (and can be) so it reads, and diffs, line by line.
+func str(s string) string {
+	if strings.Contains(s, "\n") && strconv.CanBackquote(strings.ReplaceAll(s, "\n", "")) {
+		return "`" + s + "`"
+	}
+
+	return strconv.Quote(s)
+}
+
+// null returns the Go expression for a nil value of type typ.
+func (g *generator) null(typ, static reflect.Type) string {
+	if typ == static || typ.Kind() == reflect.Interface {
+		return "nil"
+	}
+
+	return "(" + g.typeName(typ) + ")(nil)"
+}
+
+// pointer returns the Go expression for a pointer, &T{...} for composite values
+// and new(value) for anything else.
+func (g *generator) pointer(v reflect.Value, static reflect.Type, elide bool) (string, error) {
+	if v.IsNil() {
+		return g.null(v.Type(), static), nil
+	}
+
+	if err := g.enter(v); err != nil {
+		return "", err
+	}
+	defer g.leave(v)
+
+	elem := v.Elem()
+
+	// Only composite literals can have their address taken, and time.Time
+	// isn't written as one
+	composite := false
+
+	switch elem.Kind() {
+	case reflect.Struct, reflect.Array:
+		composite = elem.Type() != reflect.TypeFor[time.Time]()
+	case reflect.Slice, reflect.Map:
+		// Byte slices are written as a conversion from a string
+		composite = !elem.IsNil() && (elem.Kind() == reflect.Map || elem.Type().Elem().Kind() != reflect.Uint8)
+	}
+
+	if composite {
+		expr, err := g.value(elem, elem.Type(), elide)
+		if err != nil {
+			return "", err
+		}
+
+		if elide {
+			// []*T{{...}} is the same as []*T{&T{...}}
+			return expr, nil
+		}
+
+		return "&" + expr, nil
+	}
+
+	expr, err := g.value(elem, nil, false)
+	if err != nil {
+		return "", err
+	}
+
+	return "new(" + expr + ")", nil
+}
+
+// time returns the Go expression for a [time.Time].
+func (g *generator) time(t time.Time) string {
+	pkg := g.use("time", "time")
+
+	if t.IsZero() {
+		return pkg + ".Time{}"
+	}
+
+	location := pkg + ".UTC"
+	if t.Location() != time.UTC {
+		name, offset := t.Zone()
+		location = fmt.Sprintf("%s.FixedZone(%q, %d)", pkg, name, offset)
+	}
+
+	return fmt.Sprintf(
+		"%[1]s.Date(%[2]d, %[1]s.%[3]s, %[4]d, %[5]d, %[6]d, %[7]d, %[8]d, %[9]s)",
+		pkg, t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), location,
+	)
+}
+
+// structure returns the Go composite literal for a struct.
+func (g *generator) structure(v reflect.Value, elide bool) (string, error) {
+	typ := v.Type()
+
+	var fields []string
+
+	for i := range v.NumField() {
+		field := typ.Field(i)
+		if !field.IsExported() || v.Field(i).IsZero() {
+			continue
+		}
+
+		expr, err := g.value(v.Field(i), field.Type, false)
+		if err != nil {
+			return "", fmt.Errorf("%s.%s: %w", typ, field.Name, err)
+		}
+
+		fields = append(fields, field.Name+": "+expr)
+	}
+
+	// An empty literal would assert nothing about it e.g. a netip.Addr
+	if len(fields) == 0 && !v.IsZero() {
+		return "", fmt.Errorf("cannot represent a %s as a Go literal, its state is unexported", typ)
+	}
+
+	return g.literal(typ, fields, elide), nil
+}
+
+// sequence returns the Go composite literal for a slice or array.
+func (g *generator) sequence(v reflect.Value, static reflect.Type, elide bool) (string, error) {
+	typ := v.Type()
+
+	if v.Kind() == reflect.Slice {
+		if v.IsNil() {
+			return g.null(typ, static), nil
+		}
+
+		if typ.Elem().Kind() == reflect.Uint8 {
+			// Bytes are almost always text of some sort
+			return g.typeName(typ) + "(" + str(string(v.Bytes())) + ")", nil
+		}
+
+		if err := g.enter(v); err != nil {
+			return "", err
+		}
+		defer g.leave(v)
+	}
+
+	elements := make([]string, 0, v.Len())
+
+	for i := range v.Len() {
+		expr, err := g.value(v.Index(i), typ.Elem(), true)
+		if err != nil {
+			return "", fmt.Errorf("[%d]: %w", i, err)
+		}
+
+		elements = append(elements, expr)
+	}
+
+	return g.literal(typ, elements, elide), nil
+}
+
+// mapping returns the Go composite literal for a map, sorted by key.
+func (g *generator) mapping(v reflect.Value, static reflect.Type, elide bool) (string, error) {
+	typ := v.Type()
+
+	if v.IsNil() {
+		return g.null(typ, static), nil
+	}
+
+	if err := g.enter(v); err != nil {
+		return "", err
+	}
+	defer g.leave(v)
+
+	type entry struct {
+		key   reflect.Value
+		expr  string
+		value string
+	}
+
+	entries := make([]entry, 0, v.Len())
+
+	for _, key := range v.MapKeys() {
+		keyExpr, err := g.value(key, typ.Key(), true)
+		if err != nil {
+			return "", err
+		}
+
+		valueExpr, err := g.value(v.MapIndex(key), typ.Elem(), true)
+		if err != nil {
+			return "", fmt.Errorf("[%s]: %w", keyExpr, err)
+		}
+
+		entries = append(entries, entry{key: key, expr: keyExpr, value: valueExpr})
+	}
+
+	slices.SortFunc(entries, func(a, b entry) int {
+		if c := compare(a.key, b.key); c != 0 {
+			return c
+		}
+
+		return cmp.Compare(a.expr, b.expr)
+	})
+
+	elements := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		elements = append(elements, entry.expr+": "+entry.value)
+	}
+
+	return g.literal(typ, elements, elide), nil
+}
+
+// literal returns a composite literal of type typ with the given elements, one
+// per line, omitting the type if elide is true.
+func (g *generator) literal(typ reflect.Type, elements []string, elide bool) string {
+	buf := &strings.Builder{}
+
+	if !elide {
+		buf.WriteString(g.typeName(typ))
+	}
+
+	buf.WriteByte('{')
+
+	if len(elements) > 0 {
+		buf.WriteByte('\n')
+
+		for _, element := range elements {
+			buf.WriteString(element)
+			buf.WriteString(",\n")
+		}
+	}
+
+	buf.WriteByte('}')
+
+	return buf.String()
+}
+
+// enter marks v as being generated, returning an error if it already was, in
+// which case it's a cycle which can't be written as a literal.
+func (g *generator) enter(v reflect.Value) error {
+	key := visit{typ: v.Type(), ptr: v.Pointer()}
+	if g.visiting[key] {
+		return errors.New("cannot represent a cyclic value as a Go literal")
+	}
+
+	g.visiting[key] = true
+
+	return nil
+}
+
+// leave marks v as no longer being generated.
+func (g *generator) leave(v reflect.Value) {
+	delete(g.visiting, visit{typ: v.Type(), ptr: v.Pointer()})
+}
+
+// typeName returns the Go syntax for typ, qualified with its package name and
+// recording the import if it's declared outside the universe scope.
+func (g *generator) typeName(typ reflect.Type) string {
+	if typ == reflect.TypeFor[[]byte]() {
+		return "[]byte"
+	}
+
+	if typ.Name() != "" {
+		if typ.PkgPath() == "" {
+			// Predeclared e.g. int, string, error
+			return typ.Name()
+		}
+
+		name := typ.Name()
+		pkg := g.use(typ.PkgPath(), strings.TrimSuffix(typ.String(), "."+name))
+
+		// The type arguments of a generic type are named with their full import
+		// paths e.g. Box[go/token.Pos]
+		if i := strings.IndexByte(name, '['); i >= 0 {
+			name = name[:i] + g.qualify(name[i:])
+		}
+
+		return pkg + "." + name
+	}
+
+	switch typ.Kind() {
+	case reflect.Pointer:
+		return "*" + g.typeName(typ.Elem())
+	case reflect.Slice:
+		return "[]" + g.typeName(typ.Elem())
+	case reflect.Array:
+		return "[" + strconv.Itoa(typ.Len()) + "]" + g.typeName(typ.Elem())
+	case reflect.Map:
+		return "map[" + g.typeName(typ.Key()) + "]" + g.typeName(typ.Elem())
+	case reflect.Chan:
+		switch typ.ChanDir() {
+		case reflect.RecvDir:
+			return "<-chan " + g.typeName(typ.Elem())
+		case reflect.SendDir:
+			return "chan<- " + g.typeName(typ.Elem())
+		default:
+			return "chan " + g.typeName(typ.Elem())
+		}
+	case reflect.Interface:
+		if typ.NumMethod() == 0 {
+			return "any"
+		}
+
+		return typ.String()
+	case reflect.Struct:
+		// Embedded fields and tags are part of the type, without them the literal
+		// couldn't be assigned back to it
+		fields := make([]string, 0, typ.NumField())
+		for i := range typ.NumField() {
+			field := typ.Field(i)
+
+			decl := g.typeName(field.Type)
+			if !field.Anonymous {
+				decl = field.Name + " " + decl
+			}
+
+			if field.Tag != "" {
+				decl += " " + tag(string(field.Tag))
+			}
+
+			fields = append(fields, decl)
+		}
+
+		return "struct{" + strings.Join(fields, "; ") + "}"
+	default:
+		return typ.String()
+	}
+}
+
+// qualify returns the type arguments of a generic type, as reflect names them, with
+// each type's import path replaced by its package name e.g. [go/token.Pos] becomes
+// [token.Pos], recording the imports.
+func (g *generator) qualify(args string) string {
+	buf := &strings.Builder{}
+
+	for args != "" {
+		// A qualified type name is an import path, a dot and an identifier
+		end := strings.IndexFunc(args, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("_./~-", r)
+		})
+		if end < 0 {
+			end = len(args)
+		}
+
+		if end == 0 {
+			// Skip over any string in a struct tag, it may contain anything
+			if args[0] == '"' {
+				if quoted, err := strconv.QuotedPrefix(args); err == nil {
+					end = len(quoted)
+				}
+			}
+
+			buf.WriteString(args[:max(end, 1)])
+			args = args[max(end, 1):]
+
+			continue
+		}
+
+		word := args[:end]
+		args = args[end:]
+
+		// A variadic parameter of a func type e.g. func(...int)
+		if rest, ok := strings.CutPrefix(word, "..."); ok {
+			buf.WriteString("...")
+			word = rest
+		}
+
+		if i := strings.LastIndexByte(word, '.'); i > 0 {
+			word = g.use(word[:i], packageName(word[:i])) + word[i:]
+		}
+
+		buf.WriteString(word)
+	}
+
+	return buf.String()
+}
+
+// packageName returns the likely name of the package at importPath, the last
+// element of the path ignoring any major version suffix e.g. yaml for
+// gopkg.in/yaml.v3. If it's wrong, it doesn't matter, the import is aliased.
+func packageName(importPath string) string {
+	elems := strings.Split(importPath, "/")
+
+	name := elems[len(elems)-1]
+	if major := strings.TrimPrefix(name, "v"); len(elems) > 1 && major != name && isDigits(major) {
+		name = elems[len(elems)-2]
+	}
+
+	name, _, _ = strings.Cut(name, ".")
+
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+
+		return '_'
+	}, name)
+
+	if name == "" || !unicode.IsLetter([]rune(name)[0]) {
+		name = "pkg" + name
+	}
+
+	return name
+}
+
+// isDigits reports whether s is a non-empty run of decimal digits.
+func isDigits(s string) bool {
+	return s != "" && strings.Trim(s, "0123456789") == ""
+}
+
+// tag returns the Go string literal for a struct tag, a raw string if it can be
+// as tags almost always are.
+func tag(tag string) string {
+	if strings.Contains(tag, "`") {
+		return strconv.Quote(tag)
+	}
+
+	return "`" + tag + "`"
+}
+
+// use records that the package at importPath, called name, is referenced and
+// returns the name to refer to it by.
+//
+// That's name, unless another package referenced already has it, in which case
+// the package is imported under an alias numbered from 2 e.g. template2.
+func (g *generator) use(importPath, name string) string {
+	if existing, ok := g.imports[importPath]; ok {
+		return existing
+	}
+
+	if strings.HasSuffix(importPath, "_test") && g.err == nil {
+		g.err = fmt.Errorf("cannot refer to types declared in %s, a _test package can't be imported", importPath)
+	}
+
+	alias := name
+	for n := 2; g.names[alias]; n++ {
+		alias = name + strconv.Itoa(n)
+	}
+
+	g.imports[importPath] = alias
+	g.names[alias] = true
+
+	return alias
+}
+
+// compare orders map keys: numbers numerically, strings lexically and false before
+// true. Anything else compares equal, and is ordered by its Go expression instead.
+func compare(a, b reflect.Value) int {
+	for a.Kind() == reflect.Interface && !a.IsNil() {
+		a = a.Elem()
+	}
+
+	for b.Kind() == reflect.Interface && !b.IsNil() {
+		b = b.Elem()
+	}
+
+	if a.Kind() != b.Kind() {
+		return cmp.Compare(a.Kind(), b.Kind())
+	}
+
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cmp.Compare(a.Int(), b.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return cmp.Compare(a.Uint(), b.Uint())
+	case reflect.Float32, reflect.Float64:
+		return cmp.Compare(a.Float(), b.Float())
+	case reflect.String:
+		return cmp.Compare(a.String(), b.String())
+	case reflect.Bool:
+		switch {
+		case a.Bool() == b.Bool():
+			return 0
+		case b.Bool():
+			return -1
+		default:
+			return 1
+		}
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,147 @@
+package golit_test
+
+import (
+	"go/token"
+	htmltemplate "html/template"
+	"math"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	texttemplate "text/template"
+	"time"
+
+	"go.followtheprocess.codes/snapshot/internal/format/formattest"
+	"go.followtheprocess.codes/snapshot/internal/format/golit"
+	"go.followtheprocess.codes/test"
+)
+
+func TestFormatter(t *testing.T) {
+	nickname := "Tommy"
+
+	tests := []struct {
+		value any
+		name  string
+	}{
+		{
+			name:  "nil",
+			value: nil,
+		},
+		{
+			name:  "int",
+			value: 42,
+		},
+		{
+			name:  "typed",
+			value: int64(42),
+		},
+		{
+			name:  "string",
+			value: "line one\nline two\n",
+		},
+		{
+			name: "struct",
+			value: formattest.User{
+				Name:     "Tom",
+				Nickname: &nickname,
+				Age:      31,
+				Level:    3,
+				Joined:   time.Date(2024, time.March, 1, 9, 30, 0, 0, time.UTC),
+				Labels:   map[string]string{"team": "platform", "role": "lead"},
+				Scores:   []float64{1, 2.5, math.Inf(1)},
+				Manager:  &formattest.User{Name: "Alice", Admin: true},
+			}.WithPassword("hunter2"),
+		},
+		{
+			name:  "slice_of_structs",
+			value: []formattest.User{{Name: "Tom", Age: 31}, {Name: "Alice"}},
+		},
+		{
+			name:  "slice_of_pointers",
+			value: []*formattest.User{{Name: "Tom"}, nil},
+		},
+		{
+			name:  "map_int_keys",
+			value: map[int][]string{10: {"ten"}, 2: {"two"}, -1: nil},
+		},
+		{
+			name:  "interfaces",
+			value: []any{1, "two", 3.0, int8(4), formattest.Level(5), formattest.User{Name: "Six"}, nil, []byte("seven")},
+		},
+		{
+			name:  "snapshotter",
+			value: formattest.Session{User: "jane", Token: "secret"},
+		},
+		{
+			// Packages with the same name must be aliased, or it won't compile
+			name:  "import_clash",
+			value: []any{htmltemplate.HTML("<b>bold</b>"), texttemplate.ExecError{Name: "page"}},
+		},
+		{
+			// Tags and embedding are part of an unnamed struct type
+			name: "unnamed_struct",
+			value: []struct {
+				time.Duration
+
+				Name string `json:"name"`
+			}{{Duration: time.Second, Name: "one"}},
+		},
+		{
+			// Type arguments are named with their full import paths by reflect
+			name: "generic",
+			value: []any{
+				formattest.Box[token.Pos]{V: 3},
+				formattest.Box[map[string][]*time.Duration]{},
+				formattest.Box[formattest.Box[func(...token.Pos)]]{},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test.ColorEnabled(os.Getenv("CI") == "")
+
+			path := filepath.Join("testdata", "TestFormatter", tt.name+".snap.go")
+
+			want, err := os.ReadFile(path)
+			test.Ok(t, err)
+
+			got, err := golit.NewFormatter().Format(tt.value)
+			test.Ok(t, err)
+
+			test.DiffBytes(t, got, want)
+		})
+	}
+}
+
+func TestFormatterErrors(t *testing.T) {
+	type node struct {
+		Next *node
+	}
+
+	cyclic := &node{}
+	cyclic.Next = cyclic
+
+	type local struct {
+		Name string
+	}
+
+	tests := []struct {
+		value any
+		name  string
+	}{
+		{name: "chan", value: make(chan int)},
+		{name: "func", value: func() {}},
+		{name: "cycle", value: cyclic},
+		{name: "unexported state", value: netip.MustParseAddr("1.2.3.4")},
+		{name: "nested unexported state", value: []formattest.User{formattest.User{}.WithPassword("hunter2")}},
+		{name: "test package", value: local{Name: "local"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := golit.NewFormatter().Format(tt.value)
+			test.Err(t, err)
+		})
+	}
+}
@@ -0,0 +1,16 @@
+package snapshots
+
+import (
+	"go/token"
+	"time"
+
+	"go.followtheprocess.codes/snapshot/internal/format/formattest"
+)
+
+var snapshot = []any{
+	formattest.Box[token.Pos]{
+		V: 3,
+	},
+	formattest.Box[map[string][]*time.Duration]{},
+	formattest.Box[formattest.Box[func(...token.Pos)]]{},
+}
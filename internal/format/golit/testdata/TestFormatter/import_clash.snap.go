@@ -0,0 +1,13 @@
+package snapshots
+
+import (
+	"html/template"
+	template2 "text/template"
+)
+
+var snapshot = []any{
+	template.HTML("<b>bold</b>"),
+	template2.ExecError{
+		Name: "page",
+	},
+}
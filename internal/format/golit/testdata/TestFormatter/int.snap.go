@@ -0,0 +1,3 @@
+package snapshots
+
+var snapshot = 42
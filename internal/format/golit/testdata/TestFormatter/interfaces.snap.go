@@ -0,0 +1,16 @@
+package snapshots
+
+import "go.followtheprocess.codes/snapshot/internal/format/formattest"
+
+var snapshot = []any{
+	1,
+	"two",
+	3.0,
+	int8(4),
+	formattest.Level(5),
+	formattest.User{
+		Name: "Six",
+	},
+	nil,
+	[]byte("seven"),
+}
@@ -0,0 +1,11 @@
+package snapshots
+
+var snapshot = map[int][]string{
+	-1: nil,
+	2: {
+		"two",
+	},
+	10: {
+		"ten",
+	},
+}
@@ -0,0 +1,10 @@
+package snapshots
+
+import "go.followtheprocess.codes/snapshot/internal/format/formattest"
+
+var snapshot = []*formattest.User{
+	{
+		Name: "Tom",
+	},
+	nil,
+}
@@ -0,0 +1,13 @@
+package snapshots
+
+import "go.followtheprocess.codes/snapshot/internal/format/formattest"
+
+var snapshot = []formattest.User{
+	{
+		Name: "Tom",
+		Age:  31,
+	},
+	{
+		Name: "Alice",
+	},
+}
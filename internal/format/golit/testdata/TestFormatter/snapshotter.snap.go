@@ -0,0 +1,6 @@
+package snapshots
+
+var snapshot = map[string]string{
+	"token": "<redacted>",
+	"user":  "jane",
+}
@@ -0,0 +1,5 @@
+package snapshots
+
+var snapshot = `line one
+line two
+`
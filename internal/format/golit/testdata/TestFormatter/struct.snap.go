@@ -0,0 +1,29 @@
+package snapshots
+
+import (
+	"math"
+	"time"
+
+	"go.followtheprocess.codes/snapshot/internal/format/formattest"
+)
+
+var snapshot = formattest.User{
+	Joined: time.Date(2024, time.March, 1, 9, 30, 0, 0, time.UTC),
+	Manager: &formattest.User{
+		Name:  "Alice",
+		Admin: true,
+	},
+	Labels: map[string]string{
+		"role": "lead",
+		"team": "platform",
+	},
+	Name:     "Tom",
+	Nickname: new("Tommy"),
+	Scores: []float64{
+		1.0,
+		2.5,
+		math.Inf(1),
+	},
+	Age:   31,
+	Level: 3,
+}
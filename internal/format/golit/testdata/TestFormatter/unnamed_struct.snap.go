@@ -0,0 +1,13 @@
+package snapshots
+
+import "time"
+
+var snapshot = []struct {
+	time.Duration
+	Name string `json:"name"`
+}{
+	{
+		Duration: 1000000000,
+		Name:     "one",
+	},
+}
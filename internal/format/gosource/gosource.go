@@ -0,0 +1,130 @@
+// Package gosource provides a formatter for snapshots of Go source code, e.g. the
+// output of a code generator, normalised with gofmt.
+package gosource
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"slices"
+	"strings"
+
+	snapformat "go.followtheprocess.codes/snapshot/internal/format"
+)
+
+// Formatter implements [snapshot.Formatter] and returns a gofmt'd Go source
+// snapshot format.
+type Formatter struct{}
+
+// NewFormatter returns a new Go source Formatter.
+func NewFormatter() Formatter {
+	return Formatter{}
+}
+
+// Ext returns the file extension for a Go source snapshot.
+//
+// It isn't just ".go" so the snapshots aren't compiled along with the package.
+func (f Formatter) Ext() string {
+	return ".snap.go.txt"
+}
+
+// Format returns a snapshot of the value, which must be Go source code as a
+// string or []byte, formatted by [format.Source] exactly as gofmt would.
+//
+// The source may be a whole file, or a list of declarations or statements. Source
+// that doesn't parse is an error, listing each syntax error by line and column.
+func (f Formatter) Format(value any) ([]byte, error) {
+	var source []byte
+
+	switch value := snapformat.Snapshot(value).(type) {
+	case string:
+		source = []byte(value)
+	case []byte:
+		source = value
+	default:
+		return nil, fmt.Errorf("a Go source snapshot must be a string or []byte, got %T", value)
+	}
+
+	formatted, err := format.Source(source)
+	if err != nil {
+		list := syntaxErrors(source)
+		if len(list) == 0 {
+			return nil, fmt.Errorf("could not format Go source: %w", err)
+		}
+
+		lines := make([]string, 0, len(list))
+		for _, e := range list {
+			lines = append(lines, fmt.Sprintf("line %d, column %d: %s", e.Pos.Line, e.Pos.Column, e.Msg))
+		}
+
+		return nil, fmt.Errorf("invalid Go source:\n\t%s", strings.Join(lines, "\n\t"))
+	}
+
+	return formatted, nil
+}
+
+// wrapper turns a fragment of Go source into a whole file, as [format.Source] does.
+type wrapper struct {
+	prefix string // Written before the fragment
+	suffix string // Written after the fragment
+	next   string // The error meaning the source is the next kind of fragment, if there is one
+}
+
+// syntaxErrors returns the syntax errors in source, parsed just as [format.Source]
+// parses it: as a file, or failing that as declarations or statements wrapped in
+// a file. Positions are in source itself, not the wrapper, and any past the end
+// of it (e.g. at the closing brace wrapped around statements) are at its end.
+func syntaxErrors(source []byte) scanner.ErrorList {
+	wrappers := []wrapper{
+		{next: "expected 'package'"},
+		{prefix: "package p;", next: "expected declaration"},
+		{prefix: "package p; func _() {", suffix: "\n\n}"},
+	}
+
+	for _, w := range wrappers {
+		src := slices.Concat([]byte(w.prefix), source, []byte(w.suffix))
+
+		_, err := parser.ParseFile(token.NewFileSet(), "", src, parser.ParseComments|parser.SkipObjectResolution)
+		if err == nil {
+			return nil
+		}
+
+		if w.next != "" && strings.Contains(err.Error(), w.next) {
+			continue
+		}
+
+		var list scanner.ErrorList
+		if !errors.As(err, &list) {
+			return nil
+		}
+
+		for _, e := range list {
+			offset := e.Pos.Offset - len(w.prefix)
+			if offset >= len(source) {
+				offset = len(source)
+				e.Msg = strings.Replace(e.Msg, "found '}'", "found EOF", 1)
+			}
+
+			e.Pos.Line, e.Pos.Column = position(source, max(offset, 0))
+		}
+
+		return list
+	}
+
+	return nil
+}
+
+// position returns the line and column, both starting at 1, of the byte at offset
+// in source.
+func position(source []byte, offset int) (line, column int) {
+	before := source[:offset]
+
+	line = bytes.Count(before, []byte("\n")) + 1
+	column = offset - bytes.LastIndexByte(before, '\n')
+
+	return line, column
+}
@@ -0,0 +1,92 @@
+package gosource_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.followtheprocess.codes/snapshot/internal/format/gosource"
+	"go.followtheprocess.codes/test"
+)
+
+func TestFormatter(t *testing.T) {
+	tests := []struct {
+		value any
+		name  string
+	}{
+		{
+			name: "file",
+			value: "// Code generated by gen. DO NOT EDIT.\n\npackage   gen\n\nimport \"fmt\"\n" +
+				"type Colour int\nconst (\n\tRed Colour = iota\n\tGreen\n)\n" +
+				"func (c Colour) String() string {\nswitch c {\ncase Red: return \"red\"\n" +
+				"case Green:\n  return \"green\"\n}\nreturn fmt.Sprintf(\"Colour(%d)\", int(c))\n}\n",
+		},
+		{
+			name:  "declarations",
+			value: []byte("type Point struct {X int\nY    int}\n\nfunc (p Point) Add(q Point) Point {return Point{p.X+q.X, p.Y+q.Y}}"),
+		},
+		{
+			name:  "statements",
+			value: "x := 1\nfor i:=0;i<10;i++{\nx*=2}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test.ColorEnabled(os.Getenv("CI") == "")
+
+			path := filepath.Join("testdata", "TestFormatter", tt.name+".snap.go.txt")
+
+			want, err := os.ReadFile(path)
+			test.Ok(t, err)
+
+			got, err := gosource.NewFormatter().Format(tt.value)
+			test.Ok(t, err)
+
+			test.DiffBytes(t, got, want)
+		})
+	}
+}
+
+func TestFormatterErrors(t *testing.T) {
+	tests := []struct {
+		value any
+		name  string
+		want  string
+	}{
+		{
+			name:  "not source",
+			value: 42,
+			want:  "a Go source snapshot must be a string or []byte, got int",
+		},
+		{
+			name:  "syntax error",
+			value: "package gen\n\nfunc broken() {\n\treturn 1 +\n}\n",
+			want:  "invalid Go source:\n\tline 5, column 1: expected operand, found '}'",
+		},
+		{
+			// Positions are in the statements, not the file they're wrapped in
+			name:  "statements error",
+			value: "x := 1\ny := (\n",
+			want:  "invalid Go source:\n\tline 3, column 1: expected operand, found EOF",
+		},
+		{
+			name:  "statements error first line",
+			value: "x := )\n",
+			want:  "invalid Go source:\n\tline 1, column 6: expected operand, found ')'\n\tline 2, column 1: expected ';', found 'EOF'",
+		},
+		{
+			name:  "declarations error",
+			value: "type T struct{}\n\nfunc (T) M( {}\n",
+			want:  "invalid Go source:\n\tline 3, column 13: expected ')', found '{'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := gosource.NewFormatter().Format(tt.value)
+			test.Err(t, err)
+			test.Equal(t, err.Error(), tt.want)
+		})
+	}
+}
@@ -0,0 +1,119 @@
+// Package hexdump provides a formatter for binary snapshots, written as an xxd
+// style hex dump.
+package hexdump
+
+import (
+	"bytes"
+	"encoding"
+	"fmt"
+	"reflect"
+
+	"go.followtheprocess.codes/snapshot/internal/format"
+)
+
+const (
+	// width is the number of bytes on each line of the dump.
+	width = 16
+
+	// group is the number of bytes in each group of hex digits.
+	group = 2
+)
+
+// Formatter implements [snapshot.Formatter] and returns a hex dump
+// snapshot format.
+type Formatter struct{}
+
+// NewFormatter returns a new hex dump Formatter.
+func NewFormatter() Formatter {
+	return Formatter{}
+}
+
+// Ext returns the file extension for a hex dump snapshot.
+func (f Formatter) Ext() string {
+	return ".snap.hex"
+}
+
+// Format returns a hex dump snapshot of the value, which must be a []byte (or any
+// other slice of bytes), a string or implement [encoding.BinaryMarshaler].
+//
+// The dump starts with the length in bytes, followed by a line for every 16 bytes
+// in the same layout as xxd: the offset, the bytes in hex in groups of 2, and the
+// bytes as ASCII with anything unprintable shown as a '.'. So a change to the data
+// only changes the lines it's on.
+func (f Formatter) Format(value any) ([]byte, error) {
+	data, err := binary(format.Snapshot(value))
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+
+	unit := "bytes"
+	if len(data) == 1 {
+		unit = "byte"
+	}
+
+	fmt.Fprintf(buf, "%d %s\n", len(data), unit)
+
+	for offset := 0; offset < len(data); offset += width {
+		line(buf, offset, data[offset:min(offset+width, len(data))])
+	}
+
+	return buf.Bytes(), nil
+}
+
+// binary returns the bytes to dump for value.
+//
+// Named byte slices, like [encoding/json.RawMessage], and strings are dumped as
+// they are, unless they encode themselves.
+func binary(value any) ([]byte, error) {
+	if marshaler, ok := value.(encoding.BinaryMarshaler); ok {
+		data, err := marshaler.MarshalBinary()
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode value: %w", err)
+		}
+
+		return data, nil
+	}
+
+	v := reflect.ValueOf(value)
+
+	switch {
+	case v.Kind() == reflect.String:
+		return []byte(v.String()), nil
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		return v.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("a hex dump snapshot must be a []byte, string or encoding.BinaryMarshaler, got %T", value)
+	}
+}
+
+// line writes a line of the dump for chunk, found at offset.
+func line(buf *bytes.Buffer, offset int, chunk []byte) {
+	fmt.Fprintf(buf, "%08x:", offset)
+
+	for i := range width {
+		if i%group == 0 {
+			buf.WriteByte(' ')
+		}
+
+		if i < len(chunk) {
+			fmt.Fprintf(buf, "%02x", chunk[i])
+		} else {
+			// Pad a short last line so the ASCII column still lines up
+			buf.WriteString("  ")
+		}
+	}
+
+	buf.WriteString("  ")
+
+	for _, b := range chunk {
+		if b < ' ' || b > '~' {
+			b = '.'
+		}
+
+		buf.WriteByte(b)
+	}
+
+	buf.WriteByte('\n')
+}
@@ -0,0 +1,73 @@
+package hexdump_test
+
+import (
+	"encoding/json"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.followtheprocess.codes/snapshot/internal/format/hexdump"
+	"go.followtheprocess.codes/test"
+)
+
+// frame is a protocol frame, a named byte slice.
+type frame []byte
+
+func TestFormatter(t *testing.T) {
+	tests := []struct {
+		value any
+		name  string
+	}{
+		{
+			name:  "empty",
+			value: []byte{},
+		},
+		{
+			name:  "one_byte",
+			value: []byte{0xff},
+		},
+		{
+			name:  "string",
+			value: "Hello, world!\n",
+		},
+		{
+			// Exactly one full line and then a short one
+			name:  "binary",
+			value: []byte("\x00\x01\x02\x03snapshot\x7f\x80\xfe\xff testing\t\r\n"),
+		},
+		{
+			name:  "named_bytes",
+			value: frame{0xca, 0xfe, 0x00, 0x01},
+		},
+		{
+			name:  "raw_message",
+			value: json.RawMessage(`{"ok":true}`),
+		},
+		{
+			name:  "binary_marshaler",
+			value: netip.MustParseAddr("192.168.0.1"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test.ColorEnabled(os.Getenv("CI") == "")
+
+			path := filepath.Join("testdata", "TestFormatter", tt.name+".snap.hex")
+
+			want, err := os.ReadFile(path)
+			test.Ok(t, err)
+
+			got, err := hexdump.NewFormatter().Format(tt.value)
+			test.Ok(t, err)
+
+			test.DiffBytes(t, got, want)
+		})
+	}
+}
+
+func TestFormatterError(t *testing.T) {
+	_, err := hexdump.NewFormatter().Format(42)
+	test.Err(t, err)
+}
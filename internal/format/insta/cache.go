@@ -0,0 +1,122 @@
+package insta
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"sync"
+	"time"
+)
+
+// files is the process wide cache of parsed source files.
+//
+// A table driven test calls Snap from the same line of the same file for every
+// case, so without it we'd parse (and walk) the same file over and over again.
+var files = &cache{sources: make(map[string]*source)} //nolint:gochecknoglobals // Process wide by design
+
+// cache is a concurrency safe cache of parsed Go source files, keyed by path
+// and invalidated when the file's modification time changes.
+type cache struct {
+	sources map[string]*source
+	mu      sync.Mutex
+}
+
+// get returns the parsed source file at path, parsing it if it isn't already
+// cached or has been modified since it was.
+func (c *cache) get(path string) (*source, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot: could not parse %s: %w", path, err)
+	}
+
+	c.mu.Lock()
+
+	src, ok := c.sources[path]
+	if !ok || !src.modTime.Equal(info.ModTime()) {
+		src = &source{
+			path:    path,
+			modTime: info.ModTime(),
+			helpers: make(map[int]bool),
+			calls:   make(map[lookup]*ast.CallExpr),
+		}
+		c.sources[path] = src
+	}
+
+	c.mu.Unlock()
+
+	// Parse outside the cache lock so parallel tests in different files
+	// don't wait on each other, callers of the same file wait for the first
+	src.once.Do(src.parse)
+
+	if src.err != nil {
+		return nil, src.err
+	}
+
+	return src, nil
+}
+
+// lookup is the key for a call expression lookup in a source file.
+type lookup struct {
+	callee string
+	line   int
+}
+
+// source is a parsed Go source file, along with the results of any lookups
+// done in it.
+//
+// Once parsed, the AST is only ever read so it's safe to share between goroutines.
+type source struct {
+	modTime time.Time
+	err     error
+	fileSet *token.FileSet
+	file    *ast.File
+	helpers map[int]bool
+	calls   map[lookup]*ast.CallExpr
+	path    string
+	once    sync.Once
+	mu      sync.Mutex
+}
+
+// parse parses the source file, recording any error.
+func (s *source) parse() {
+	s.fileSet = token.NewFileSet()
+
+	s.file, s.err = parser.ParseFile(s.fileSet, s.path, nil, parser.SkipObjectResolution)
+	if s.err != nil {
+		s.err = fmt.Errorf("snapshot: could not parse %s: %w", s.path, s.err)
+	}
+}
+
+// isHelper reports whether the function enclosing line marks itself as a test
+// helper, see [isHelper].
+func (s *source) isHelper(line int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	helper, ok := s.helpers[line]
+	if !ok {
+		helper = isHelper(s.fileSet, s.file, line)
+		s.helpers[line] = helper
+	}
+
+	return helper
+}
+
+// findCall returns the call expression on line most likely to be a call to
+// callee, see [findCall].
+func (s *source) findCall(line int, callee string) *ast.CallExpr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := lookup{line: line, callee: callee}
+
+	call, ok := s.calls[key]
+	if !ok {
+		call = findCall(s.fileSet, s.file, line, callee)
+		s.calls[key] = call
+	}
+
+	return call
+}
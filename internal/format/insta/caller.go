@@ -0,0 +1,289 @@
+package insta
+
+import (
+	"bytes"
+	"errors"
+	"go/ast"
+	"go/format"
+	"go/printer"
+	"go/token"
+	"runtime"
+	"strings"
+)
+
+const (
+	// maxFrames is the maximum number of stack frames searched for the call site
+	// of a snapshot assertion, far deeper than any sane chain of test helpers.
+	maxFrames = 64
+
+	// assertion is the name of the snapshot assertion, snapshot.Runner.Snap, whose
+	// argument is the expression being snapshotted.
+	assertion = "Snap"
+)
+
+// site is the resolved call site of a snapshot assertion.
+type site struct {
+	file       string // Path to the source file containing the call, absolute unless it couldn't be resolved
+	expression string // The Go expression being snapshotted, may be empty
+	line       int    // The line the call is on
+}
+
+// callSite resolves the call site of the snapshot assertion that called Format.
+//
+// Starting at the caller of Format (the assertion e.g. snapshot.Runner.Snap), it
+// walks up the stack skipping any functions that mark themselves as test helpers
+// with a call to Helper(), just as testing.TB does when reporting failures. So if
+// Snap is wrapped in an assertRender(t, value) helper, the call site is the call to
+// assertRender in the test, not the call to Snap inside the helper.
+//
+// If the call is to the assertion itself, the expression is its argument,
+// otherwise it's the whole call to the outermost helper. If the call can't be
+// found, the expression is left empty rather than guessed.
+func callSite() (site, error) {
+	// Skip: 3 so runtime.Callers, callSite and Format are all skipped
+	const skip = 3
+
+	pcs := make([]uintptr, maxFrames)
+
+	n := runtime.Callers(skip, pcs)
+	if n == 0 {
+		return site{}, errors.New("could not get runtime.Callers info")
+	}
+
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var stack []runtime.Frame
+
+	for {
+		frame, more := frames.Next()
+
+		// Method values call through an autogenerated wrapper, which isn't
+		// part of anyone's source code
+		if !wrapper(frame) {
+			stack = append(stack, frame)
+		}
+
+		if !more {
+			break
+		}
+	}
+
+	// The first frame is the assertion itself, the function we're looking for a call to
+	if len(stack) < 2 {
+		return site{}, errors.New("could not find the caller of the snapshot assertion")
+	}
+
+	callee := stack[0]
+	direct := true
+
+	for i, frame := range stack[1:] {
+		file, found := resolve(frame.File)
+		if !found {
+			// Built with -trimpath and we couldn't map the file back to the source,
+			// the expression is best effort so record what we can rather than fail
+			return site{file: frame.File, line: frame.Line}, nil
+		}
+
+		src, err := files.get(file)
+		if err != nil {
+			// Same again, the source could have been removed or may not parse
+			return site{file: file, line: frame.Line}, nil //nolint:nilerr // Best effort, see above
+		}
+
+		// The test function itself is never skipped even if it calls Helper(), just
+		// as testing.TB never reports a failure from inside the testing package
+		next := i + 2
+		if next < len(stack) && !test(stack[next]) && src.isHelper(frame.Line) {
+			callee = frame
+			direct = false
+
+			continue
+		}
+
+		call := src.findCall(frame.Line, name(callee.Function))
+
+		s := site{file: file, line: frame.Line}
+
+		switch {
+		case call == nil:
+			// Couldn't find it, the expression is best effort so don't fail
+		case direct && name(callee.Function) != assertion:
+			// Format wasn't called by Snap, so there's no telling what its
+			// arguments are
+		case direct && len(call.Args) > 0:
+			s.expression = render(src.fileSet, call.Args[0])
+		default:
+			s.expression = render(src.fileSet, call)
+		}
+
+		return s, nil
+	}
+
+	return site{}, errors.New("could not find the caller of the snapshot assertion")
+}
+
+// wrapper reports whether frame is a compiler generated wrapper function, such
+// as the one created for a method value.
+func wrapper(frame runtime.Frame) bool {
+	return frame.File == "<autogenerated>" || strings.HasSuffix(frame.Function, "-fm")
+}
+
+// test reports whether frame is in the testing package, e.g. testing.tRunner, in
+// which case the frame before it is the test function.
+func test(frame runtime.Frame) bool {
+	return strings.HasPrefix(frame.Function, "testing.")
+}
+
+// name returns the unqualified name of a function as reported by the runtime e.g.
+// "Snap" for "go.followtheprocess.codes/snapshot.Runner.Snap".
+func name(function string) string {
+	function = strings.TrimSuffix(function, "-fm")
+
+	if i := strings.LastIndexByte(function, '.'); i >= 0 {
+		return function[i+1:]
+	}
+
+	return function
+}
+
+// isHelper reports whether the innermost function (or function literal) enclosing
+// line in file marks itself as a test helper, by calling Helper() in its body.
+func isHelper(fileSet *token.FileSet, file *ast.File, line int) bool {
+	body := enclosing(fileSet, file, line)
+	if body == nil {
+		return false
+	}
+
+	for _, stmt := range body.List {
+		expr, ok := stmt.(*ast.ExprStmt)
+		if !ok {
+			continue
+		}
+
+		call, ok := expr.X.(*ast.CallExpr)
+		if !ok || len(call.Args) != 0 {
+			continue
+		}
+
+		if selector, ok := call.Fun.(*ast.SelectorExpr); ok && selector.Sel.Name == "Helper" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// findCall returns the call expression spanning line in file that is most likely
+// to be a call to the function called callee, or nil if there isn't one.
+//
+// A call spanning multiple lines may be reported on any one of them, so every call
+// in the function enclosing line that spans it is a candidate. A call to a function
+// named callee is preferred. Failing that, if callee is the snapshot assertion it
+// may have been called through a method value or variable, so the outermost call
+// of a variable or field with a single argument, as Snap has, is used.
+func findCall(fileSet *token.FileSet, file *ast.File, line int, callee string) *ast.CallExpr {
+	// Only look inside the enclosing function, otherwise in a subtest the outermost
+	// call spanning the line is always the call to t.Run
+	var root ast.Node = file
+	if body := enclosing(fileSet, file, line); body != nil {
+		root = body
+	}
+
+	var outermost *ast.CallExpr
+
+	for node := range ast.Preorder(root) {
+		call, ok := node.(*ast.CallExpr)
+		if !ok || !contains(fileSet, call, line) {
+			continue
+		}
+
+		if called(call) == callee {
+			return call
+		}
+
+		if outermost == nil && callee == assertion && variable(call) {
+			outermost = call
+		}
+	}
+
+	return outermost
+}
+
+// enclosing returns the body of the innermost function (or function literal)
+// enclosing line in file, or nil if there isn't one.
+func enclosing(fileSet *token.FileSet, file *ast.File, line int) *ast.BlockStmt {
+	var body *ast.BlockStmt
+
+	for node := range ast.Preorder(file) {
+		var candidate *ast.BlockStmt
+
+		switch fn := node.(type) {
+		case *ast.FuncDecl:
+			candidate = fn.Body
+		case *ast.FuncLit:
+			candidate = fn.Body
+		default:
+			continue
+		}
+
+		// Preorder visits enclosing functions before those nested within them, so
+		// the last one containing the line is the innermost
+		if candidate != nil && contains(fileSet, candidate, line) {
+			body = candidate
+		}
+	}
+
+	return body
+}
+
+// called returns the name of the function being called by call, or "" if it
+// isn't called by name.
+func called(call *ast.CallExpr) string {
+	switch fun := call.Fun.(type) {
+	case *ast.Ident:
+		return fun.Name
+	case *ast.SelectorExpr:
+		return fun.Sel.Name
+	case *ast.IndexExpr:
+		// Generic function with explicit type arguments e.g. check[int](x)
+		return called(&ast.CallExpr{Fun: fun.X})
+	case *ast.IndexListExpr:
+		return called(&ast.CallExpr{Fun: fun.X})
+	default:
+		return ""
+	}
+}
+
+// variable reports whether call could be a call of a method value or function held
+// in a variable or field e.g. check(value) or s.check(value), with a single argument.
+func variable(call *ast.CallExpr) bool {
+	if len(call.Args) != 1 || call.Ellipsis.IsValid() {
+		return false
+	}
+
+	switch call.Fun.(type) {
+	case *ast.Ident, *ast.SelectorExpr:
+		return true
+	default:
+		return false
+	}
+}
+
+// contains reports whether node spans line.
+func contains(fileSet *token.FileSet, node ast.Node, line int) bool {
+	return fileSet.Position(node.Pos()).Line <= line && line <= fileSet.Position(node.End()).Line
+}
+
+// render pretty prints node back to Go source.
+func render(fileSet *token.FileSet, node ast.Node) string {
+	buf := &bytes.Buffer{}
+
+	if err := format.Node(buf, fileSet, node); err != nil {
+		// If we couldn't print a go fmt compatible version, just dump the
+		// normal string representation
+		buf.Reset()
+		printer.Fprint(buf, fileSet, node)
+	}
+
+	return buf.String()
+}
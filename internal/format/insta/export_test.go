@@ -0,0 +1,9 @@
+package insta
+
+// Resolve is resolve for the module containing dir, rather than the one containing
+// the working directory, which is only found once per process.
+func Resolve(file, dir string) (string, bool) {
+	modulePath, root := findModule(dir)
+
+	return resolveIn(file, modulePath, root)
+}
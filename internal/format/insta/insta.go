@@ -8,23 +8,17 @@ package insta
 
 import (
 	"bytes"
-	"errors"
 	"fmt"
-	"go/ast"
-	"go/format"
-	"go/parser"
-	"go/printer"
-	"go/token"
 	"io"
 	"os"
 	"path/filepath"
-	"runtime"
+	"reflect"
 
+	"go.followtheprocess.codes/snapshot/internal/format"
+	yamlformat "go.followtheprocess.codes/snapshot/internal/format/yaml"
 	"go.yaml.in/yaml/v4"
 )
 
-const yamlIndent = 2
-
 // Metadata holds the metadata for an insta-compatible snapshot.
 //
 // The metadata is rendered as the first yaml document in the snapshot,
@@ -34,11 +28,31 @@ type Metadata struct {
 	// the snapshot.
 	Source string `yaml:"source"`
 
+	// AssertionLine is the line in Source of the assertion that generated the
+	// snapshot.
+	//
+	// Like recent versions of insta, snapshot doesn't write this as it changes
+	// every time code above the assertion moves, but it is preserved when reading
+	// snapshots written by other tools.
+	AssertionLine int `yaml:"assertion_line,omitempty"`
+
 	// Description is a brief, human readable description of the snapshot.
 	Description string `yaml:"description,omitempty"`
 
 	// Expression is the Go expression that generated the snapshot.
 	Expression string `yaml:"expression,omitempty"`
+
+	// Info is arbitrary structured context attached to the snapshot, e.g. the
+	// inputs to a table driven test.
+	Info any `yaml:"info,omitempty"`
+
+	// InputFile is the path to the file used as input to the snapshot, for
+	// snapshots generated from a set of input files.
+	InputFile string `yaml:"input_file,omitempty"`
+
+	// SnapshotKind is the kind of snapshot, insta writes "binary" for binary
+	// snapshots and omits it (or writes "text") otherwise.
+	SnapshotKind string `yaml:"snapshot_kind,omitempty"`
 }
 
 // Snapshot is the Go representation of an insta snapshot.
@@ -49,30 +63,45 @@ type Snapshot struct {
 
 // save serialises the snapshot to it's yaml representation.
 func (s Snapshot) save(w io.Writer) error {
-	encoder := yaml.NewEncoder(w)
-	encoder.SetIndent(yamlIndent)
+	if err := yamlformat.Dump(w, yamlformat.Config{}, s.Metadata, s.Value); err != nil {
+		return fmt.Errorf("could not write snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// load deserialises a snapshot from it's yaml representation.
+func load(r io.Reader) (Snapshot, error) {
+	decoder := yaml.NewDecoder(r)
 
-	if err := encoder.Encode(s.Metadata); err != nil {
-		return fmt.Errorf("could not write snapshot metadata: %w", err)
+	var snap Snapshot
+
+	if err := decoder.Decode(&snap.Metadata); err != nil {
+		return Snapshot{}, fmt.Errorf("could not read snapshot metadata: %w", err)
 	}
 
-	if err := encoder.Encode(s.Value); err != nil {
-		return fmt.Errorf("could not write snapshot value: %w", err)
+	if err := decoder.Decode(&snap.Value); err != nil {
+		return Snapshot{}, fmt.Errorf("could not read snapshot value: %w", err)
 	}
 
-	return encoder.Close()
+	return snap, nil
 }
 
 // Formatter implements the [snapshot.Formatter] interface and returns an
 // insta-compatible snapshot format.
 type Formatter struct {
+	info        any
 	description string
 }
 
 // NewFormatter returns a new [Formatter].
-func NewFormatter(description string) Formatter {
+//
+// The description and info, which may be nil, are written to the metadata of
+// every snapshot.
+func NewFormatter(description string, info any) Formatter {
 	return Formatter{
 		description: description,
+		info:        info,
 	}
 }
 
@@ -81,66 +110,110 @@ func (f Formatter) Ext() string {
 	return ".snap"
 }
 
-// Format returns the insta formatted snapshot for a value.
-func (f Formatter) Format(value any) ([]byte, error) {
-	// Skip: 2 so Format and caller are both skipped
-	const skip = 2
+// Lossy returns a description of each part of value that would be silently
+// encoded as an empty {}, see [format.Lossy].
+func (f Formatter) Lossy(value any) []string {
+	return format.Lossy(value, "yaml")
+}
 
-	_, source, line, ok := runtime.Caller(skip)
-	if !ok {
-		return nil, errors.New("could not get runtime.Caller info")
+// Decode decodes the value from an insta formatted snapshot back into
+// structured data, the metadata is not included.
+func (f Formatter) Decode(data []byte) (any, error) {
+	snap, err := load(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
 	}
 
-	// Parse the file
-	fileSet := token.NewFileSet()
+	return snap.Value, nil
+}
 
-	file, err := parser.ParseFile(fileSet, source, nil, parser.SkipObjectResolution)
+// Equal implements [snapshot.Comparer] for insta formatted snapshots, comparing
+// only their values so that the snapshot still matches if the metadata changes
+// e.g. the test file is renamed or the description reworded.
+func (f Formatter) Equal(old, current []byte) (bool, error) {
+	before, err := load(bytes.NewReader(old))
 	if err != nil {
-		return nil, fmt.Errorf("snapshot: could not parse %s: %w", source, err)
+		return false, fmt.Errorf("could not load old snapshot: %w", err)
 	}
 
-	var expression string
+	after, err := load(bytes.NewReader(current))
+	if err != nil {
+		return false, fmt.Errorf("could not load new snapshot: %w", err)
+	}
 
-	// Let's go find it
-	for node := range ast.Preorder(file) {
-		// If it's not on the right line we know it's not it
-		start := fileSet.Position(node.Pos())
-		if start.Line != line {
-			continue
-		}
+	// Compare the values as they would be written, that way the comparison
+	// doesn't depend on Go types (e.g. int vs uint64) that the YAML doesn't preserve
+	oldValue, err := encode(before.Value)
+	if err != nil {
+		return false, err
+	}
 
-		// We're looking for the call to snapshot.Snap(value)
-		call, ok := node.(*ast.CallExpr)
-		if !ok {
-			continue
-		}
+	newValue, err := encode(after.Value)
+	if err != nil {
+		return false, err
+	}
 
-		selector, ok := call.Fun.(*ast.SelectorExpr)
-		if !ok {
-			continue
-		}
+	return bytes.Equal(oldValue, newValue), nil
+}
 
-		if selector.Sel.Name != "Snap" {
-			continue
-		}
+// Drift returns a human readable description of each difference between the
+// metadata of the old and current snapshots, or nil if there are none (or
+// either cannot be loaded).
+func (f Formatter) Drift(old, current []byte) []string {
+	before, err := load(bytes.NewReader(old))
+	if err != nil {
+		return nil
+	}
 
-		// Found it!
-		// By now we know it's a function call, and we know the function the user is calling
-		// is snapshot.Runner.Snap(value), so now we can pull out the expression 'value'
-		//
-		arg := call.Args[0] // The signature of Snap takes a single argument
+	after, err := load(bytes.NewReader(current))
+	if err != nil {
+		return nil
+	}
 
-		// Pretty print the arg node to display it
-		buf := &bytes.Buffer{}
+	fields := []struct {
+		name   string
+		before string
+		after  string
+	}{
+		{name: "source", before: before.Metadata.Source, after: after.Metadata.Source},
+		{name: "description", before: before.Metadata.Description, after: after.Metadata.Description},
+		{name: "expression", before: before.Metadata.Expression, after: after.Metadata.Expression},
+		{name: "input_file", before: before.Metadata.InputFile, after: after.Metadata.InputFile},
+		{name: "snapshot_kind", before: before.Metadata.SnapshotKind, after: after.Metadata.SnapshotKind},
+	}
 
-		err = format.Node(buf, fileSet, arg)
-		if err != nil {
-			// If we couldn't print a go fmt compatible version, just dump the
-			// normal string representation
-			printer.Fprint(buf, fileSet, arg)
+	var drift []string
+
+	for _, field := range fields {
+		if field.before != field.after {
+			drift = append(drift, fmt.Sprintf("%s: %q → %q", field.name, field.before, field.after))
 		}
+	}
 
-		expression = buf.String()
+	// Info can be anything so rather than try and summarise it, just say it changed
+	if !reflect.DeepEqual(before.Metadata.Info, after.Metadata.Info) {
+		drift = append(drift, "info has changed")
+	}
+
+	return drift
+}
+
+// encode returns the yaml encoding of a snapshot value.
+func encode(value any) ([]byte, error) {
+	buf := &bytes.Buffer{}
+
+	if err := yamlformat.Dump(buf, yamlformat.Config{}, value); err != nil {
+		return nil, fmt.Errorf("could not encode snapshot value: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Format returns the insta formatted snapshot for a value.
+func (f Formatter) Format(value any) ([]byte, error) {
+	caller, err := callSite()
+	if err != nil {
+		return nil, err
 	}
 
 	cwd, err := os.Getwd()
@@ -148,9 +221,14 @@ func (f Formatter) Format(value any) ([]byte, error) {
 		return nil, fmt.Errorf("could not get cwd: %w", err)
 	}
 
-	relativeSource, err := filepath.Rel(cwd, source)
-	if err != nil {
-		return nil, fmt.Errorf("could not make %s relative to %s: %w", source, cwd, err)
+	// If the source couldn't be resolved (e.g. -trimpath), it's already
+	// relative to something so just use it as is
+	relativeSource := filepath.ToSlash(caller.file)
+	if filepath.IsAbs(caller.file) {
+		relativeSource, err = filepath.Rel(cwd, caller.file)
+		if err != nil {
+			return nil, fmt.Errorf("could not make %s relative to %s: %w", caller.file, cwd, err)
+		}
 	}
 
 	snap := Snapshot{
@@ -158,7 +236,8 @@ func (f Formatter) Format(value any) ([]byte, error) {
 		Metadata: Metadata{
 			Source:      relativeSource,
 			Description: f.description,
-			Expression:  expression,
+			Expression:  caller.expression,
+			Info:        f.info,
 		},
 	}
 
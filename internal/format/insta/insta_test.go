@@ -3,8 +3,12 @@ package insta_test
 import (
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"sync"
 	"testing"
 
+	"go.followtheprocess.codes/snapshot/internal/format/formattest"
 	"go.followtheprocess.codes/snapshot/internal/format/insta"
 	"go.followtheprocess.codes/test"
 )
@@ -12,6 +16,7 @@ import (
 func TestFormatter(t *testing.T) {
 	tests := []struct {
 		value       any
+		info        any
 		description string
 		name        string
 	}{
@@ -33,6 +38,25 @@ func TestFormatter(t *testing.T) {
 			name:  "struct",
 			value: newPerson(),
 		},
+		{
+			name:        "multiline",
+			description: "CLI output",
+			value:       "Usage: demo [OPTIONS]\n\nOptions:\n  --help  Show help\n",
+		},
+		{
+			name:        "info",
+			description: "With structured context",
+			value:       "a string",
+			info: map[string]any{
+				"input":  []int{1, 2, 3},
+				"reason": "table driven",
+			},
+		},
+		{
+			// Snapshotters are honoured wherever they are
+			name:  "snapshotter",
+			value: map[string]any{"session": formattest.Session{User: "jane", Token: "secret"}},
+		},
 	}
 
 	for _, tt := range tests {
@@ -42,7 +66,7 @@ func TestFormatter(t *testing.T) {
 			want, err := os.ReadFile(path)
 			test.Ok(t, err)
 
-			got, err := snap(tt.value, tt.description)
+			got, err := snap(tt.value, tt.description, tt.info)
 			test.Ok(t, err)
 
 			test.DiffBytes(t, got, want)
@@ -50,6 +74,99 @@ func TestFormatter(t *testing.T) {
 	}
 }
 
+func TestFormatterConcurrent(t *testing.T) {
+	// Parallel table driven tests all Snap from the same line of the same file,
+	// which must be safe and give the same result every time
+	want, err := snap(newPerson(), "Concurrent", nil)
+	test.Ok(t, err)
+
+	const n = 50
+
+	results := make([][]byte, n)
+	errs := make([]error, n)
+
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Go(func() {
+			results[i], errs[i] = snap(newPerson(), "Concurrent", nil)
+		})
+	}
+
+	wg.Wait()
+
+	for i := range n {
+		test.Ok(t, errs[i])
+		test.DiffBytes(t, results[i], want)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	// Under -trimpath the runtime reports import paths rather than files on disk,
+	// which must be mapped back to the file within the module
+	root := t.TempDir()
+	pkg := filepath.Join(root, "pkg")
+	file := filepath.Join(pkg, "pkg_test.go")
+
+	test.Ok(t, os.MkdirAll(pkg, 0o755))
+	test.Ok(t, os.WriteFile(filepath.Join(root, "go.mod"), []byte("// The module\nmodule \"example.com/mod\" // Quoted\n\ngo 1.25\n"), 0o644))
+	test.Ok(t, os.WriteFile(file, []byte("package pkg_test\n"), 0o644))
+
+	tests := []struct {
+		name string // Name of the test case
+		file string // The file as reported by the runtime
+		want string // The file on disk it should resolve to
+		ok   bool   // Whether it should resolve at all
+	}{
+		{
+			name: "module relative",
+			file: "example.com/mod/pkg/pkg_test.go",
+			want: file,
+			ok:   true,
+		},
+		{
+			name: "absolute",
+			file: file,
+			want: file,
+			ok:   true,
+		},
+		{
+			name: "missing",
+			file: "example.com/mod/pkg/missing_test.go",
+			ok:   false,
+		},
+		{
+			name: "another module",
+			file: "example.com/other/pkg/pkg_test.go",
+			ok:   false,
+		},
+		{
+			// Shares a prefix with the module path but isn't in it
+			name: "prefixed module",
+			file: "example.com/module/pkg/pkg_test.go",
+			ok:   false,
+		},
+		{
+			name: "standard library",
+			file: "testing/testing.go",
+			ok:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Tests run from their package directory, below the module root
+			got, ok := insta.Resolve(tt.file, pkg)
+			test.Equal(t, ok, tt.ok)
+			test.Equal(t, got, tt.want)
+		})
+	}
+}
+
+func TestResolveNoModule(t *testing.T) {
+	_, ok := insta.Resolve("example.com/mod/pkg/pkg_test.go", t.TempDir())
+	test.False(t, ok)
+}
+
 type person struct {
 	Name     string
 	Friends  []string
@@ -66,12 +183,74 @@ func newPerson() person {
 	}
 }
 
-// snap is a function that just calls insta Format, it has to be here because we
-// do a runtime.Caller and skip 2 as this is how it will be used in practice
-// inside the snapshot.Runner.Snap method so we need to wrap .Format in another function
-// so it has 2 callers, otherwise the source gets populated as GOROOT/testing etc.
-func snap(value any, description string) ([]byte, error) {
-	formatter := insta.NewFormatter(description)
+// snap is a function that just calls insta Format, it has to be here because
+// Format looks for the call to whatever called it, which in practice is the
+// snapshot.Runner.Snap method, so we need to wrap .Format in another function
+// to stand in for Snap, otherwise the source gets populated as GOROOT/testing etc.
+func snap(value any, description string, info any) ([]byte, error) {
+	formatter := insta.NewFormatter(description, info)
 
 	return formatter.Format(value)
 }
+
+func TestDecode(t *testing.T) {
+	formatter := insta.NewFormatter("A description", nil)
+
+	content, err := snap(newPerson(), "A description", nil)
+	test.Ok(t, err)
+
+	got, err := formatter.Decode(content)
+	test.Ok(t, err)
+
+	want := map[string]any{
+		"name":     "Obi Wan Kenobi",
+		"age":      34,
+		"employed": true,
+		"friends":  []any{"Yoda", "Qui Gon Jin", "Mace Windu"},
+	}
+
+	test.EqualFunc(t, got, any(want), reflect.DeepEqual)
+
+	// The value document is required
+	_, err = formatter.Decode([]byte("source: insta_test.go\n"))
+	test.Err(t, err)
+}
+
+func TestEqual(t *testing.T) {
+	formatter := insta.NewFormatter("A description", nil)
+
+	old := []byte("source: old_test.go\ndescription: Old\n---\nvalue: a string\n")
+	renamed := []byte("source: new_test.go\ndescription: New\nexpression: value\n---\nvalue: a string\n")
+	changed := []byte("source: old_test.go\ndescription: Old\n---\nvalue: another string\n")
+
+	equal, err := formatter.Equal(old, renamed)
+	test.Ok(t, err)
+	test.True(t, equal, test.Context("metadata changes should not affect equality"))
+
+	equal, err = formatter.Equal(old, changed)
+	test.Ok(t, err)
+	test.False(t, equal, test.Context("value changes should affect equality"))
+
+	_, err = formatter.Equal([]byte("source: old_test.go\n"), changed)
+	test.Err(t, err)
+
+	want := []string{
+		`source: "old_test.go" → "new_test.go"`,
+		`description: "Old" → "New"`,
+		`expression: "" → "value"`,
+	}
+
+	test.EqualFunc(t, formatter.Drift(old, renamed), want, slices.Equal)
+	test.Equal(t, len(formatter.Drift(old, changed)), 0)
+
+	// Fields written by insta itself are understood too
+	insta := []byte("source: old_test.go\nassertion_line: 12\ndescription: Old\ninfo:\n  n: 1\nsnapshot_kind: text\n---\nvalue: a string\n")
+
+	equal, err = formatter.Equal(old, insta)
+	test.Ok(t, err)
+	test.True(t, equal, test.Context("insta metadata should not affect equality"))
+
+	want = []string{`snapshot_kind: "" → "text"`, "info has changed"}
+
+	test.EqualFunc(t, formatter.Drift(old, insta), want, slices.Equal)
+}
@@ -0,0 +1,106 @@
+package insta
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// module is the module containing the package under test, found lazily the first
+// time it's needed and at most once per process.
+var module = sync.OnceValues(func() (modulePath, root string) { //nolint:gochecknoglobals // Only needs finding once per process
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", ""
+	}
+
+	return findModule(dir)
+})
+
+// resolve returns the path on disk of a source file as reported by the runtime,
+// reporting whether it could be found.
+//
+// Normally that's the absolute path to the file, but when the test binary is
+// built with -trimpath it's the file's import path e.g.
+// go.followtheprocess.codes/snapshot/snapshot_test.go. Tests are run from their
+// package directory, which is inside their module, so if the import path is in
+// the same module it can be mapped back to the file on disk.
+func resolve(file string) (string, bool) {
+	modulePath, root := module()
+
+	return resolveIn(file, modulePath, root)
+}
+
+// resolveIn is [resolve] for the module modulePath, whose root directory is root.
+func resolveIn(file, modulePath, root string) (string, bool) {
+	if filepath.IsAbs(file) {
+		return file, true
+	}
+
+	if modulePath == "" {
+		return "", false
+	}
+
+	rest, ok := strings.CutPrefix(file, modulePath+"/")
+	if !ok {
+		// Another module, or the standard library, which we can't find
+		return "", false
+	}
+
+	resolved := filepath.Join(root, filepath.FromSlash(rest))
+	if _, err := os.Stat(resolved); err != nil {
+		return "", false
+	}
+
+	return resolved, true
+}
+
+// findModule returns the module path and root directory of the module containing
+// dir, or empty strings if it can't be found.
+func findModule(dir string) (modulePath, root string) {
+	for {
+		if modulePath := readModulePath(filepath.Join(dir, "go.mod")); modulePath != "" {
+			return modulePath, dir
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", ""
+		}
+
+		dir = parent
+	}
+}
+
+// readModulePath returns the module path declared in the go.mod file at file, or
+// "" if it can't be read.
+func readModulePath(file string) string {
+	f, err := os.Open(file)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "//")
+
+		rest, ok := strings.CutPrefix(strings.TrimSpace(line), "module")
+		if !ok || rest == "" || (rest[0] != ' ' && rest[0] != '\t' && rest[0] != '"') {
+			continue
+		}
+
+		modulePath := strings.TrimSpace(rest)
+		if unquoted, err := strconv.Unquote(modulePath); err == nil {
+			modulePath = unquoted
+		}
+
+		return path.Clean(modulePath)
+	}
+
+	return ""
+}
@@ -1,15 +1,39 @@
 // Package json provides a JSON formatter for snapshots.
 package json
 
-import "encoding/json"
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"go.followtheprocess.codes/snapshot/internal/format"
+)
+
+// limits are the things encoding/json can't encode, beyond those no encoder can.
+var limits = format.Limits{NonFiniteFloats: true, MapKeys: true} //nolint:gochecknoglobals // Effectively a constant
+
+// defaultIndent is the indent for each level of nesting if the config doesn't set one.
+const defaultIndent = "  "
+
+// Config controls the layout of JSON snapshots, the zero value is the default
+// layout.
+type Config struct {
+	Indent            string // Indent for each level of nesting, "" means two spaces
+	DisableHTMLEscape bool   // Write <, > and & as is rather than as unicode escapes
+	SortKeys          bool   // Sort the keys of every object, not just those of maps
+	TrailingNewline   bool   // End the document with a newline
+}
 
 // Formatter implements [snapshot.Formatter] and returns a JSON
 // snapshot format.
-type Formatter struct{}
+type Formatter struct {
+	config Config
+}
 
-// NewFormatter returns a new JSON Formatter.
-func NewFormatter() Formatter {
-	return Formatter{}
+// NewFormatter returns a new JSON Formatter, laying out snapshots
+// according to config.
+func NewFormatter(config Config) Formatter {
+	return Formatter{config: config}
 }
 
 // Ext returns the file extension for a JSON snapshot.
@@ -17,7 +41,117 @@ func (f Formatter) Ext() string {
 	return ".snap.json"
 }
 
+// Lossy returns a description of each part of value that would be silently
+// encoded as an empty {}, see [format.Lossy].
+func (f Formatter) Lossy(value any) []string {
+	return format.Lossy(value, "json")
+}
+
 // Format returns a JSON formatted snapshot of the value.
+//
+// A [format.Snapshotter] is encoded as its snapshot, anything encoding/json can't
+// encode, chans, funcs, NaN etc. is replaced by a placeholder and errors are
+// encoded as their message, see [format.Normalise].
+//
+// Whenever the value has to be decoded and re-encoded (see below, and
+// [Config.SortKeys]), numbers are decoded as [json.Number] so no precision is lost.
+//
+// If the value is already an encoded JSON document, a [json.RawMessage] or a string
+// or []byte holding a JSON object or array (e.g. an HTTP response body), it's
+// parsed and re-encoded canonically with sorted keys and consistent indentation,
+// rather than encoded as a string.
 func (f Formatter) Format(value any) ([]byte, error) {
-	return json.MarshalIndent(value, "", "  ")
+	// A Snapshotter may well present itself as an already encoded document
+	value = format.Snapshot(value)
+
+	if document, ok := encoded(value); ok {
+		decoded, err := f.Decode(document)
+		if err != nil {
+			return nil, err
+		}
+
+		value = decoded
+	} else {
+		value = format.Normalise(value, limits)
+	}
+
+	if f.config.SortKeys {
+		// encoding/json writes struct fields in declaration order but always sorts
+		// map keys, so round trip the value through a map
+		document, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+
+		decoded, err := f.Decode(document)
+		if err != nil {
+			return nil, err
+		}
+
+		value = decoded
+	}
+
+	indent := f.config.Indent
+	if indent == "" {
+		indent = defaultIndent
+	}
+
+	buf := &bytes.Buffer{}
+
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(!f.config.DisableHTMLEscape)
+	encoder.SetIndent("", indent)
+
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+
+	// The encoder always ends with a newline
+	if !f.config.TrailingNewline {
+		buf.Truncate(buf.Len() - 1)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// encoded returns the JSON document held in value, reporting whether value
+// holds one at all.
+//
+// Only objects and arrays in strings and byte slices count, a string like "42"
+// or "true" is far more likely to just be a string.
+func encoded(value any) ([]byte, bool) {
+	var document []byte
+
+	switch value := value.(type) {
+	case json.RawMessage:
+		return value, json.Valid(value)
+	case []byte:
+		document = value
+	case string:
+		document = []byte(value)
+	default:
+		return nil, false
+	}
+
+	trimmed := bytes.TrimSpace(document)
+	if len(trimmed) == 0 || (trimmed[0] != '{' && trimmed[0] != '[') {
+		return nil, false
+	}
+
+	return document, json.Valid(document)
+}
+
+// Decode decodes a JSON snapshot back into structured data.
+//
+// Numbers are decoded as [json.Number] so no precision is lost.
+func (f Formatter) Decode(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("failed to decode value: %w", err)
+	}
+
+	return value, nil
 }
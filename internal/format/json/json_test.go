@@ -1,10 +1,16 @@
 package json_test
 
 import (
+	"bytes"
+	stdjson "encoding/json"
+	"errors"
+	"math"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 
+	"go.followtheprocess.codes/snapshot/internal/format/formattest"
 	"go.followtheprocess.codes/snapshot/internal/format/json"
 	"go.followtheprocess.codes/test"
 )
@@ -17,8 +23,9 @@ type config struct {
 
 func TestFormatter(t *testing.T) {
 	tests := []struct {
-		value any
-		name  string
+		value  any
+		name   string
+		config json.Config
 	}{
 		{
 			name:  "empty",
@@ -54,6 +61,86 @@ func TestFormatter(t *testing.T) {
 				},
 			},
 		},
+		{
+			// Things the encoder can't handle are replaced with placeholders rather
+			// than failing the test
+			name: "unsupported",
+			value: struct {
+				Callback func()      `json:"callback"`
+				Events   chan int    `json:"events"`
+				Err      error       `json:"err"`
+				Labels   map[any]any `json:"labels"`
+				Nothing  func()      `json:"nothing"`
+				Name     string      `json:"name"`
+				Ratio    float64     `json:"ratio"`
+			}{
+				Callback: func() {},
+				Events:   make(chan int),
+				Err:      errors.New("file not found"),
+				Labels:   map[any]any{1: "one", "two": 2},
+				Name:     "unsupported",
+				Ratio:    math.NaN(),
+			},
+			config: json.Config{DisableHTMLEscape: true},
+		},
+		{
+			// Already encoded documents are re-encoded canonically, not as a string,
+			// and numbers must survive the round trip exactly
+			name:  "raw_message",
+			value: stdjson.RawMessage(`{"zebra": 1,"apple":{"b":[1,2],"a":12345678901234567890}}`),
+		},
+		{
+			name:  "raw_bytes",
+			value: []byte(`[{"b": true, "a": null}]`),
+		},
+		{
+			name:  "raw_string",
+			value: "  {\"status\":\"ok\",  \"code\": 200}\n",
+		},
+		{
+			// Valid JSON, but far more likely to be an ordinary string
+			name:  "scalar_string",
+			value: "42",
+		},
+		{
+			name:  "invalid_json_string",
+			value: "{not json",
+		},
+		{
+			name:   "html",
+			value:  map[string]string{"body": "<p>Tom & Jerry</p>"},
+			config: json.Config{DisableHTMLEscape: true},
+		},
+		{
+			name:   "indent",
+			value:  map[string][]int{"numbers": {1, 2}},
+			config: json.Config{Indent: "\t"},
+		},
+		{
+			name: "sort_keys",
+			value: struct {
+				Zebra string `json:"zebra"`
+				Apple struct {
+					Size uint64 `json:"size"`
+					Kind string `json:"kind"`
+				} `json:"apple"`
+			}{Zebra: "z", Apple: struct {
+				Size uint64 `json:"size"`
+				Kind string `json:"kind"`
+			}{Size: 18446744073709551615, Kind: "fruit"}},
+			config: json.Config{SortKeys: true},
+		},
+		{
+			name:   "trailing_newline",
+			value:  []int{1, 2},
+			config: json.Config{TrailingNewline: true},
+		},
+		{
+			// Snapshotters are honoured wherever they are
+			name:   "snapshotter",
+			value:  map[string]any{"session": formattest.Session{User: "jane", Token: "secret"}},
+			config: json.Config{DisableHTMLEscape: true},
+		},
 	}
 
 	for _, tt := range tests {
@@ -65,7 +152,7 @@ func TestFormatter(t *testing.T) {
 			want, err := os.ReadFile(path)
 			test.Ok(t, err)
 
-			got, err := json.NewFormatter().Format(tt.value)
+			got, err := json.NewFormatter(tt.config).Format(tt.value)
 			test.Ok(t, err)
 
 			test.DiffBytes(t, got, want)
@@ -73,9 +160,48 @@ func TestFormatter(t *testing.T) {
 	}
 }
 
+func TestFormatterTrailingNewline(t *testing.T) {
+	// The golden file comparison doesn't care about trailing newlines, so check
+	// explicitly that the option adds exactly one and its absence doesn't
+	got, err := json.NewFormatter(json.Config{}).Format([]int{1})
+	test.Ok(t, err)
+	test.False(t, bytes.HasSuffix(got, []byte("\n")))
+
+	got, err = json.NewFormatter(json.Config{TrailingNewline: true}).Format([]int{1})
+	test.Ok(t, err)
+	test.True(t, bytes.HasSuffix(got, []byte("]\n")))
+}
+
+// broken is a value that fails to marshal itself.
+type broken struct{}
+
+func (broken) MarshalJSON() ([]byte, error) {
+	return nil, errors.New("broken")
+}
+
 func TestFormatterError(t *testing.T) {
-	// Channels cannot be marshalled, the error from encoding/json must
-	// be propagated rather than swallowed.
-	_, err := json.NewFormatter().Format(make(chan int))
+	// The error from encoding/json must be propagated rather than swallowed.
+	_, err := json.NewFormatter(json.Config{}).Format(broken{})
+	test.Err(t, err)
+}
+
+func TestDecode(t *testing.T) {
+	formatter := json.NewFormatter(json.Config{})
+
+	content, err := formatter.Format(config{Name: "snapshot", Version: 2, Tags: []string{"go"}})
+	test.Ok(t, err)
+
+	got, err := formatter.Decode(content)
+	test.Ok(t, err)
+
+	want := map[string]any{
+		"name":    "snapshot",
+		"version": stdjson.Number("2"),
+		"tags":    []any{"go"},
+	}
+
+	test.EqualFunc(t, got, any(want), reflect.DeepEqual)
+
+	_, err = formatter.Decode([]byte("{not json"))
 	test.Err(t, err)
 }
@@ -0,0 +1,145 @@
+package format
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Lossy returns a description of each part of value that a formatter encoding
+// only exported fields (like encoding/json) would silently encode as an empty
+// {}, even though it isn't empty at all.
+//
+// That's a non-zero struct with no exported fields, that doesn't encode itself
+// with a Snapshot, MarshalJSON, MarshalYAML or MarshalText method, e.g. an
+// atomic.Int64 or a type whose fields are all private. A snapshot of one asserts
+// nothing, so the test passes however it changes.
+//
+// Fields are treated as the encoder would, given the key of the struct tags it
+// reads e.g. "json". Fields tagged "-" are left out, and the exported fields of
+// embedded structs are promoted even if the embedded struct itself is unexported.
+func Lossy(value any, tag string) []string {
+	l := lossChecker{visiting: make(map[uintptr]bool), tag: tag}
+	l.check(reflect.ValueOf(value), "")
+
+	return l.lost
+}
+
+// lossChecker holds the state of a single call to [Lossy].
+type lossChecker struct {
+	visiting map[uintptr]bool // Pointers and maps on the current path, to avoid cycling forever
+	tag      string           // Key of the struct tags the encoder reads
+	lost     []string         // Descriptions of the lossy values found so far
+}
+
+// check checks v, found at path, and everything reachable from it.
+func (l *lossChecker) check(v reflect.Value, path string) {
+	if v.IsValid() && v.Kind() == reflect.Pointer && l.visiting[v.Pointer()] {
+		return
+	}
+
+	// What's encoded is the snapshot, see Normalise
+	if snapshot, ok := SnapshotOf(v); ok {
+		v = snapshot
+	}
+
+	if !v.IsValid() || marshals(v.Type()) {
+		return
+	}
+
+	// Errors are encoded as their message, see Normalise
+	if _, ok := asError(v); ok {
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		l.check(v.Elem(), path)
+	case reflect.Pointer:
+		if v.IsNil() || l.visiting[v.Pointer()] {
+			return
+		}
+
+		l.visiting[v.Pointer()] = true
+		defer delete(l.visiting, v.Pointer())
+
+		l.check(v.Elem(), path)
+	case reflect.Struct:
+		l.structure(v, path)
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return
+		}
+
+		for i := range v.Len() {
+			l.check(v.Index(i), path+"["+strconv.Itoa(i)+"]")
+		}
+	case reflect.Map:
+		if v.IsNil() || l.visiting[v.Pointer()] {
+			return
+		}
+
+		l.visiting[v.Pointer()] = true
+		defer delete(l.visiting, v.Pointer())
+
+		for iter := v.MapRange(); iter.Next(); {
+			l.check(iter.Value(), fmt.Sprintf("%s[%#v]", path, iter.Key()))
+		}
+	}
+}
+
+// structure checks a struct found at path, and its encoded fields.
+func (l *lossChecker) structure(v reflect.Value, path string) {
+	if !l.fields(Addressable(v), path) && !v.IsZero() {
+		typ := strings.ReplaceAll(v.Type().String(), "interface {}", "any")
+		l.lost = append(l.lost, fmt.Sprintf("%s (%s)", root(path), typ))
+	}
+}
+
+// fields checks the fields of the struct v found at path, reporting whether the
+// encoder encodes any of them at all.
+func (l *lossChecker) fields(v reflect.Value, path string) bool {
+	typ := v.Type()
+	encoded := false
+
+	for i := range typ.NumField() {
+		field := typ.Field(i)
+
+		if name, _, _ := strings.Cut(field.Tag.Get(l.tag), ","); name == "-" {
+			continue
+		}
+
+		// The fields of an embedded unexported struct are promoted into this one
+		if embedded, ok := promoted(v.Field(i), field); ok {
+			if embedded.IsValid() && l.fields(embedded, path) {
+				encoded = true
+			}
+
+			continue
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		encoded = true
+
+		l.check(v.Field(i), path+"."+field.Name)
+	}
+
+	return encoded
+}
+
+// root returns path, or "." if path is the root of the value.
+func root(path string) string {
+	if path == "" {
+		return "."
+	}
+
+	if strings.HasPrefix(path, "[") {
+		return "." + path
+	}
+
+	return path
+}
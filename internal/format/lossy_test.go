@@ -0,0 +1,116 @@
+package format_test
+
+import (
+	"errors"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.followtheprocess.codes/snapshot/internal/format"
+	"go.followtheprocess.codes/test"
+)
+
+type opaque struct {
+	secret string
+}
+
+type promotes struct {
+	A int
+	B string
+}
+
+func TestLossy(t *testing.T) {
+	tests := []struct {
+		value any
+		name  string
+		want  []string
+	}{
+		{
+			name:  "nil",
+			value: nil,
+			want:  nil,
+		},
+		{
+			name:  "exported",
+			value: struct{ Name string }{Name: "fine"},
+			want:  nil,
+		},
+		{
+			// Nothing is lost encoding a zero value as {}
+			name:  "zero",
+			value: struct{ Opaque opaque }{},
+			want:  nil,
+		},
+		{
+			name:  "top level",
+			value: &opaque{secret: "hidden"},
+			want:  []string{". (format_test.opaque)"},
+		},
+		{
+			name: "nested",
+			value: map[string][]any{
+				"items": {1, opaque{secret: "hidden"}},
+			},
+			want: []string{`.["items"][1] (format_test.opaque)`},
+		},
+		{
+			name: "fields",
+			value: struct {
+				Count  *atomic.Int64
+				Opaque opaque
+			}{Count: atomicInt(3), Opaque: opaque{secret: "hidden"}},
+			want: []string{".Count (atomic.Int64)", ".Opaque (format_test.opaque)"},
+		},
+		{
+			// The encoder promotes the fields of an embedded unexported struct
+			name:  "embedded unexported",
+			value: struct{ promotes }{promotes: promotes{A: 1, B: "x"}},
+			want:  nil,
+		},
+		{
+			// Unless there aren't any to promote
+			name:  "embedded opaque",
+			value: struct{ opaque }{opaque: opaque{secret: "hidden"}},
+			want:  []string{". (struct { format_test.opaque })"},
+		},
+		{
+			// Excluded from the snapshot explicitly, so nothing is lost
+			name: "tagged out",
+			value: struct {
+				Count *atomic.Int64 `json:"-"`
+				Name  string        `json:"name"`
+			}{Count: atomicInt(3), Name: "fine"},
+			want: nil,
+		},
+		{
+			// Its snapshot is encoded, not its fields
+			name:  "snapshotter",
+			value: token{value: "secret"},
+			want:  nil,
+		},
+		{
+			// These encode themselves so nothing is lost
+			name: "marshalers and errors",
+			value: []any{
+				time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+				errors.New("boom"),
+			},
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := format.Lossy(tt.value, "json")
+			test.EqualFunc(t, got, tt.want, slices.Equal)
+		})
+	}
+}
+
+func atomicInt(n int64) *atomic.Int64 {
+	i := &atomic.Int64{}
+	i.Store(n)
+
+	return i
+}
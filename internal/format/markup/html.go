@@ -0,0 +1,181 @@
+package markup
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// parseHTML parses an HTML document, or a fragment of one, into a tree of nodes.
+//
+// Unlike a browser, it doesn't add any missing html, head or body elements, but
+// it does close the common elements with optional end tags e.g. a <li> is closed
+// by the next one. End tags without a start tag are ignored.
+func parseHTML(document []byte) ([]*node, error) {
+	tokenizer := html.NewTokenizer(bytes.NewReader(document))
+
+	var t tree
+
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			if err := tokenizer.Err(); !errors.Is(err, io.EOF) {
+				return nil, fmt.Errorf("could not parse HTML: %w", err)
+			}
+
+			return t.roots, nil
+		case html.StartTagToken:
+			token := tokenizer.Token()
+			implied(&t, token.Data)
+			t.start(token.Data, htmlAttrs(token))
+
+			switch {
+			case void(token.Data):
+				t.end(token.Data)
+			case verbatim(token.Data):
+				if err := preserve(tokenizer, &t, token.Data); err != nil {
+					return nil, err
+				}
+			}
+		case html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			t.start(token.Data, htmlAttrs(token))
+			t.end(token.Data)
+		case html.EndTagToken:
+			t.end(tokenizer.Token().Data)
+		case html.TextToken:
+			t.text(string(tokenizer.Text()), false)
+		case html.CommentToken:
+			t.add(&node{kind: comment, text: tokenizer.Token().Data})
+		case html.DoctypeToken:
+			t.add(&node{kind: directive, text: "DOCTYPE " + tokenizer.Token().Data})
+		}
+	}
+}
+
+// preserve adds the contents of the element called name, which has just been
+// started, exactly as they are written and then ends it.
+func preserve(tokenizer *html.Tokenizer, t *tree, name string) error {
+	contents := &bytes.Buffer{}
+	depth := 0
+
+	for {
+		next := tokenizer.Next()
+		if next == html.ErrorToken {
+			if err := tokenizer.Err(); !errors.Is(err, io.EOF) {
+				return fmt.Errorf("could not parse HTML: %w", err)
+			}
+
+			break
+		}
+
+		if tag, _ := tokenizer.TagName(); string(tag) == name {
+			switch next {
+			case html.StartTagToken:
+				depth++
+			case html.EndTagToken:
+				depth--
+			}
+		}
+
+		if depth < 0 {
+			break
+		}
+
+		contents.Write(tokenizer.Raw())
+	}
+
+	if contents.Len() != 0 {
+		t.text(contents.String(), true)
+	}
+
+	t.end(name)
+
+	return nil
+}
+
+// implied closes the innermost open element if its end tag is implied by the start
+// of an element called name, e.g. a <li> by another <li> or a <p> by a <div>.
+func implied(t *tree, name string) {
+	for len(t.open) != 0 {
+		open := strings.ToLower(t.open[len(t.open)-1].name)
+
+		var closes bool
+
+		switch strings.ToLower(name) {
+		case "li":
+			closes = open == "li"
+		case "dt", "dd":
+			closes = open == "dt" || open == "dd"
+		case "tr":
+			closes = open == "tr" || open == "td" || open == "th"
+		case "td", "th":
+			closes = open == "td" || open == "th"
+		case "option":
+			closes = open == "option"
+		case "p", "div", "ul", "ol", "dl", "table", "pre", "blockquote", "form", "hr",
+			"h1", "h2", "h3", "h4", "h5", "h6", "section", "article", "header", "footer", "nav":
+			closes = open == "p"
+		}
+
+		if !closes {
+			return
+		}
+
+		t.end(t.open[len(t.open)-1].name)
+	}
+}
+
+// htmlAttrs returns the attributes of token.
+func htmlAttrs(token html.Token) []attr {
+	attrs := make([]attr, 0, len(token.Attr))
+	for _, a := range token.Attr {
+		name := a.Key
+		if a.Namespace != "" {
+			name = a.Namespace + ":" + a.Key
+		}
+
+		attrs = append(attrs, attr{name: name, value: a.Val})
+	}
+
+	return attrs
+}
+
+// void reports whether the element called name is an HTML void element, one that
+// never has any contents or an end tag.
+func void(name string) bool {
+	switch strings.ToLower(name) {
+	case "area", "base", "br", "col", "embed", "hr", "img", "input", "link", "meta", "source", "track", "wbr":
+		return true
+	default:
+		return false
+	}
+}
+
+// inline reports whether the HTML element called name is an inline element, one
+// that's laid out within the surrounding text so the whitespace around it matters.
+func inline(name string) bool {
+	switch strings.ToLower(name) {
+	case "a", "abbr", "b", "bdi", "bdo", "br", "button", "cite", "code", "data", "dfn", "em", "i", "img",
+		"input", "kbd", "label", "mark", "q", "s", "samp", "select", "small", "span", "strong", "sub", "sup",
+		"textarea", "time", "u", "var", "wbr":
+		return true
+	default:
+		return false
+	}
+}
+
+// verbatim reports whether the contents of the HTML element called name are
+// significant exactly as written.
+func verbatim(name string) bool {
+	switch strings.ToLower(name) {
+	case "pre", "textarea", "script", "style":
+		return true
+	default:
+		return false
+	}
+}
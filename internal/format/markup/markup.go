@@ -0,0 +1,386 @@
+// Package markup provides formatters for XML and HTML snapshots that parse the
+// document and write it back out canonically, so that changes to insignificant
+// whitespace, attribute order or the way empty elements are written don't break
+// the snapshot.
+package markup
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"slices"
+	"strings"
+
+	"go.followtheprocess.codes/snapshot/internal/format"
+)
+
+// indent is the indent for each level of nesting.
+const indent = "  "
+
+// Config controls how documents are parsed and written.
+type Config struct {
+	HTML bool // The documents are HTML rather than XML
+}
+
+// Formatter implements [snapshot.Formatter] and returns a canonical XML
+// or HTML snapshot format.
+type Formatter struct {
+	config Config
+}
+
+// NewFormatter returns a new markup Formatter, parsing and writing
+// documents according to config.
+func NewFormatter(config Config) Formatter {
+	return Formatter{config: config}
+}
+
+// Ext returns the file extension for a markup snapshot.
+func (f Formatter) Ext() string {
+	if f.config.HTML {
+		return ".snap.html"
+	}
+
+	return ".snap.xml"
+}
+
+// Format returns a canonical XML or HTML snapshot of the value, which should be
+// the document as a string or []byte. For XML, any other value is first encoded
+// with [xml.Marshal].
+//
+// The document is written one element per line with consistent indentation,
+// attributes sorted by name and runs of whitespace in text collapsed to a single
+// space. Only markup whitespace is collapsed, non-breaking spaces are kept.
+// Elements containing text, or HTML inline elements like <b>, are written on a
+// single line keeping the whitespace between their children, as it's significant.
+// Empty XML elements are always self-closing e.g. <item/>, and HTML void elements
+// are never e.g. <br>. The contents of HTML pre, textarea, script and style
+// elements, and of XML elements with xml:space="preserve", are kept as is.
+func (f Formatter) Format(value any) ([]byte, error) {
+	var document []byte
+
+	switch value := format.Snapshot(value).(type) {
+	case string:
+		document = []byte(value)
+	case []byte:
+		document = value
+	default:
+		if f.config.HTML {
+			return nil, fmt.Errorf("an HTML snapshot must be a string or []byte, got %T", value)
+		}
+
+		encoded, err := xml.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode value: %w", err)
+		}
+
+		document = encoded
+	}
+
+	var (
+		nodes []*node
+		err   error
+	)
+
+	if f.config.HTML {
+		nodes, err = parseHTML(document)
+	} else {
+		nodes, err = parseXML(document)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	p := printer{buf: &bytes.Buffer{}, html: f.config.HTML}
+	for _, n := range slices.DeleteFunc(nodes, blank) {
+		p.print(n, 0)
+	}
+
+	return p.buf.Bytes(), nil
+}
+
+// kind is the kind of a node in a document.
+type kind int
+
+const (
+	element   kind = iota // <name attr="value">children</name>
+	text                  // Character data, whitespace collapsed
+	raw                   // Character data to be written exactly as is
+	comment               // <!-- text -->
+	directive             // <!text> e.g. a doctype
+	procInst              // <?text?> e.g. an XML declaration
+)
+
+// attr is an attribute of an element.
+type attr struct {
+	name  string
+	value string
+}
+
+// node is a node in a parsed document.
+type node struct {
+	name     string  // Name of an element
+	text     string  // Contents of anything other than an element
+	attrs    []attr  // Attributes of an element
+	children []*node // Children of an element
+	kind     kind
+}
+
+// tree builds a tree of nodes from a stream of tokens.
+type tree struct {
+	roots []*node // The top level nodes
+	open  []*node // Elements that have been started but not ended, innermost last
+}
+
+// add adds n as a child of the innermost open element, or as a root if there
+// isn't one.
+func (t *tree) add(n *node) {
+	if len(t.open) == 0 {
+		t.roots = append(t.roots, n)
+
+		return
+	}
+
+	parent := t.open[len(t.open)-1]
+	parent.children = append(parent.children, n)
+}
+
+// text adds character data with each run of whitespace collapsed to a single
+// space, unless it's to be preserved.
+//
+// Whether whitespace is significant depends on where the text ends up, so that's
+// left to the printer, see [printer.element].
+func (t *tree) text(data string, preserve bool) {
+	if preserve {
+		t.add(&node{kind: raw, text: data})
+
+		return
+	}
+
+	if data == "" {
+		return
+	}
+
+	collapsed := strings.Join(strings.FieldsFunc(data, space), " ")
+
+	if space(rune(data[0])) {
+		collapsed = " " + collapsed
+	}
+
+	if space(rune(data[len(data)-1])) && collapsed != " " {
+		collapsed += " "
+	}
+
+	t.add(&node{kind: text, text: collapsed})
+}
+
+// start adds a new element and opens it, with its attributes sorted by name.
+func (t *tree) start(name string, attrs []attr) {
+	slices.SortStableFunc(attrs, func(a, b attr) int {
+		return strings.Compare(a.name, b.name)
+	})
+
+	n := &node{kind: element, name: name, attrs: attrs}
+	t.add(n)
+	t.open = append(t.open, n)
+}
+
+// end closes the innermost open element called name, and any opened since,
+// reporting whether there was one to close.
+func (t *tree) end(name string) bool {
+	for i := len(t.open) - 1; i >= 0; i-- {
+		if t.open[i].name == name {
+			t.open = t.open[:i]
+
+			return true
+		}
+	}
+
+	return false
+}
+
+// printer writes a tree of nodes canonically.
+type printer struct {
+	buf  *bytes.Buffer
+	html bool
+}
+
+// print writes n and its children at depth.
+func (p *printer) print(n *node, depth int) {
+	p.buf.WriteString(strings.Repeat(indent, depth))
+
+	switch n.kind {
+	case text:
+		p.buf.WriteString(escape(strings.TrimFunc(n.text, space), false))
+	case element:
+		p.element(n, depth)
+	default:
+		p.inline(n)
+	}
+
+	p.buf.WriteByte('\n')
+}
+
+// element writes an element and its children at depth.
+//
+// If it has inline content, that's text or (in HTML) inline elements like <b>, it's
+// written on a single line with any whitespace between its children kept as a
+// single space, as it's significant e.g. "Hello <b>world</b>" is not the same as
+// "Hello<b>world</b>". Only the whitespace at the very start and end is dropped.
+//
+// Otherwise each child is written on its own line, one level deeper, and any
+// whitespace between them dropped.
+func (p *printer) element(n *node, depth int) {
+	if p.open(n) {
+		return
+	}
+
+	if p.mixed(n) {
+		children := edges(n.children)
+		for _, child := range children {
+			p.inline(child)
+		}
+
+		fmt.Fprintf(p.buf, "</%s>", n.name)
+
+		return
+	}
+
+	children := slices.DeleteFunc(slices.Clone(n.children), blank)
+	if len(children) != 0 {
+		p.buf.WriteByte('\n')
+
+		for _, child := range children {
+			p.print(child, depth+1)
+		}
+
+		p.buf.WriteString(strings.Repeat(indent, depth))
+	}
+
+	fmt.Fprintf(p.buf, "</%s>", n.name)
+}
+
+// inline writes n and its children on the current line.
+func (p *printer) inline(n *node) {
+	switch n.kind {
+	case text, raw:
+		p.buf.WriteString(p.text(n))
+	case comment:
+		fmt.Fprintf(p.buf, "<!-- %s -->", strings.Join(strings.Fields(n.text), " "))
+	case directive:
+		fmt.Fprintf(p.buf, "<!%s>", strings.Join(strings.Fields(n.text), " "))
+	case procInst:
+		fmt.Fprintf(p.buf, "<?%s?>", strings.TrimSpace(n.text))
+	case element:
+		if p.open(n) {
+			return
+		}
+
+		for _, child := range n.children {
+			p.inline(child)
+		}
+
+		fmt.Fprintf(p.buf, "</%s>", n.name)
+	}
+}
+
+// open writes the start tag of an element, reporting whether that's the whole
+// element because it's empty: self-closing in XML e.g. <item/>, or an HTML void
+// element e.g. <br>.
+func (p *printer) open(n *node) bool {
+	p.buf.WriteByte('<')
+	p.buf.WriteString(n.name)
+
+	for _, a := range n.attrs {
+		fmt.Fprintf(p.buf, ` %s="%s"`, a.name, escape(a.value, true))
+	}
+
+	switch {
+	case len(n.children) == 0 && !p.html:
+		p.buf.WriteString("/>")
+
+		return true
+	case len(n.children) == 0 && void(n.name):
+		p.buf.WriteByte('>')
+
+		return true
+	default:
+		p.buf.WriteByte('>')
+
+		return false
+	}
+}
+
+// mixed reports whether n has inline content, so is written on a single line.
+func (p *printer) mixed(n *node) bool {
+	return slices.ContainsFunc(n.children, func(child *node) bool {
+		switch child.kind {
+		case raw:
+			return true
+		case text:
+			return !blank(child)
+		case element:
+			return p.html && inline(child.name)
+		default:
+			return false
+		}
+	})
+}
+
+// edges returns children without the whitespace at the start of the first child
+// and end of the last, where it's not significant.
+func edges(children []*node) []*node {
+	children = slices.Clone(children)
+
+	if first := children[0]; first.kind == text {
+		children[0] = &node{kind: text, text: strings.TrimLeft(first.text, " ")}
+	}
+
+	if last := children[len(children)-1]; last.kind == text {
+		children[len(children)-1] = &node{kind: text, text: strings.TrimRight(last.text, " ")}
+	}
+
+	return slices.DeleteFunc(children, func(child *node) bool {
+		return child.kind == text && child.text == ""
+	})
+}
+
+// blank reports whether n is text that's only whitespace.
+func blank(n *node) bool {
+	return n.kind == text && strings.TrimFunc(n.text, space) == ""
+}
+
+// space reports whether r is whitespace in markup: a space, tab, newline, carriage
+// return or form feed. Unlike [unicode.IsSpace], a non-breaking space isn't, it's
+// part of the text.
+func space(r rune) bool {
+	switch r {
+	case ' ', '\t', '\n', '\r', '\f':
+		return true
+	default:
+		return false
+	}
+}
+
+// text returns the escaped text of a text node.
+func (p *printer) text(n *node) string {
+	switch n.kind {
+	case raw:
+		return n.text
+	case text:
+		return escape(n.text, false)
+	default:
+		return ""
+	}
+}
+
+// escape escapes the characters in text that would be mistaken for markup,
+// including double quotes if it's an attribute value.
+func escape(text string, attribute bool) string {
+	replacer := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	if attribute {
+		replacer = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+	}
+
+	return replacer.Replace(text)
+}
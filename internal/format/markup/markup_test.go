@@ -0,0 +1,162 @@
+package markup_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.followtheprocess.codes/snapshot/internal/format/markup"
+	"go.followtheprocess.codes/test"
+)
+
+type envelope struct {
+	Body string `xml:"body"`
+	ID   int    `xml:"id,attr"`
+}
+
+func TestFormatter(t *testing.T) {
+	tests := []struct {
+		value  any
+		name   string
+		config markup.Config
+	}{
+		{
+			name: "xml",
+			value: `<?xml version="1.0" encoding="UTF-8"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"  id="1"><soap:Body>
+	<!--   the   request -->
+	<GetUser   b="2" a="1"><Name>  Jane
+	Doe </Name><Empty></Empty><Tags><Tag>a &amp; b</Tag></Tags></GetUser>
+</soap:Body></soap:Envelope>`,
+		},
+		{
+			// Anything that isn't already XML is marshalled first
+			name:  "xml_marshal",
+			value: envelope{ID: 1, Body: "hello"},
+		},
+		{
+			name: "html",
+			value: []byte(`<!doctype html>
+<html><head><meta charset="utf-8"><title>  Home </title>
+<style>
+  body { margin: 0; }
+</style></head>
+<body class="main"   id="top">
+<p>Hello <b>world</b>!<br/>Bye</p>
+<pre>  keep
+    this  </pre>
+<div></div>
+<ul><li>one<li>two</ul>
+</body></html>`),
+			config: markup.Config{HTML: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test.ColorEnabled(os.Getenv("CI") == "")
+
+			formatter := markup.NewFormatter(tt.config)
+
+			path := filepath.Join("testdata", "TestFormatter", tt.name+formatter.Ext())
+
+			want, err := os.ReadFile(path)
+			test.Ok(t, err)
+
+			got, err := formatter.Format(tt.value)
+			test.Ok(t, err)
+
+			test.DiffBytes(t, got, want)
+		})
+	}
+}
+
+func TestFormatterWhitespace(t *testing.T) {
+	html := markup.Config{HTML: true}
+
+	tests := []struct {
+		name   string
+		a, b   string
+		config markup.Config
+		same   bool
+	}{
+		{
+			// Render differently, so must snapshot differently
+			name:   "around inline elements",
+			config: html,
+			a:      "<p>Hello <b>world</b>!</p>",
+			b:      "<p>Hello<b>world</b> !</p>",
+			same:   false,
+		},
+		{
+			name:   "between inline elements",
+			config: html,
+			a:      "<p><b>a</b> <i>b</i></p>",
+			b:      "<p><b>a</b><i>b</i></p>",
+			same:   false,
+		},
+		{
+			// Only the layout of the source differs
+			name:   "reindented",
+			config: html,
+			a:      "<div><p>Hello <b>world</b>!</p></div>",
+			b:      "<div>\n  <p>\n    Hello   <b>world</b>!\n  </p>\n</div>\n",
+			same:   true,
+		},
+		{
+			// Non-breaking spaces are text, not whitespace to collapse
+			name:   "non-breaking spaces",
+			config: html,
+			a:      "<p>a&nbsp;&nbsp;b&nbsp;</p>",
+			b:      "<p>a b</p>",
+			same:   false,
+		},
+		{
+			name: "preserved",
+			a:    `<a xml:space="preserve">  x   y  </a>`,
+			b:    `<a xml:space="preserve">x y</a>`,
+			same: false,
+		},
+		{
+			// Only within the element with the attribute
+			name: "preserved reset",
+			a:    `<a xml:space="preserve"><b xml:space="default">  x   y  </b></a>`,
+			b:    `<a xml:space="preserve"><b xml:space="default">x y</b></a>`,
+			same: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formatter := markup.NewFormatter(tt.config)
+
+			a, err := formatter.Format(tt.a)
+			test.Ok(t, err)
+
+			b, err := formatter.Format(tt.b)
+			test.Ok(t, err)
+
+			test.Equal(t, string(a) == string(b), tt.same, test.Context("a:\n%s\nb:\n%s", a, b))
+		})
+	}
+}
+
+func TestFormatterErrors(t *testing.T) {
+	tests := []struct {
+		value  any
+		name   string
+		config markup.Config
+	}{
+		{name: "malformed xml", value: "<a><b></a>"},
+		{name: "unclosed xml", value: "<a><b></b>"},
+		{name: "unmarshallable", value: make(chan int)},
+		{name: "html not text", value: 42, config: markup.Config{HTML: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := markup.NewFormatter(tt.config).Format(tt.value)
+			test.Err(t, err)
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package markup
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// parseXML parses an XML document into a tree of nodes.
+//
+// Namespace prefixes are kept as they are written, rather than resolved to the
+// namespace URL as encoding/xml normally does.
+func parseXML(document []byte) ([]*node, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(document))
+
+	var t tree
+
+	// Whether whitespace is kept as is in each open element, because it or one it's
+	// within has xml:space="preserve" (and nothing closer has xml:space="default")
+	var preserving []bool
+
+	for {
+		token, err := decoder.RawToken()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("could not parse XML: %w", err)
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			preserve := len(preserving) != 0 && preserving[len(preserving)-1]
+
+			attrs := make([]attr, 0, len(token.Attr))
+			for _, a := range token.Attr {
+				name := xmlName(a.Name)
+				if name == "xml:space" {
+					preserve = a.Value == "preserve"
+				}
+
+				attrs = append(attrs, attr{name: name, value: a.Value})
+			}
+
+			preserving = append(preserving, preserve)
+
+			t.start(xmlName(token.Name), attrs)
+		case xml.EndElement:
+			// Unlike HTML, every element must be explicitly closed, innermost first
+			name := xmlName(token.Name)
+			if len(t.open) == 0 || t.open[len(t.open)-1].name != name {
+				line, _ := decoder.InputPos()
+
+				return nil, fmt.Errorf("could not parse XML: line %d: unexpected end element </%s>", line, name)
+			}
+
+			t.end(name)
+			preserving = preserving[:len(preserving)-1]
+		case xml.CharData:
+			if len(preserving) != 0 && preserving[len(preserving)-1] {
+				// Kept as written, so escaped here as it won't be by the printer
+				t.text(escape(string(token), false), true)
+
+				continue
+			}
+
+			t.text(string(token), false)
+		case xml.Comment:
+			t.add(&node{kind: comment, text: string(token)})
+		case xml.Directive:
+			t.add(&node{kind: directive, text: string(token)})
+		case xml.ProcInst:
+			t.add(&node{kind: procInst, text: token.Target + " " + string(token.Inst)})
+		}
+	}
+
+	if len(t.open) != 0 {
+		return nil, fmt.Errorf("could not parse XML: unclosed element <%s>", t.open[len(t.open)-1].name)
+	}
+
+	return t.roots, nil
+}
+
+// xmlName returns the name as written in the document, with its prefix if it has one.
+func xmlName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+
+	return name.Space + ":" + name.Local
+}
@@ -0,0 +1,432 @@
+package format
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go.yaml.in/yaml/v4"
+)
+
+// Limits describes what an encoder can't encode natively, over and above the
+// things no encoder can: chans, funcs, complex numbers and unsafe pointers.
+type Limits struct {
+	// NonFiniteFloats means NaN and ±Inf can't be encoded.
+	NonFiniteFloats bool
+
+	// MapKeys means map keys must be strings, integers or implement
+	// [encoding.TextMarshaler].
+	MapKeys bool
+
+	// StringKeys means map keys must be strings or implement [encoding.TextMarshaler],
+	// integers aren't allowed either.
+	StringKeys bool
+}
+
+// Normalise returns a copy of value with everything that can't be encoded within
+// limits replaced by a descriptive placeholder, so a snapshot captures all it can
+// rather than failing outright:
+//
+//   - A [Snapshotter] becomes whatever its Snapshot method returns
+//   - Chans and funcs become "<chan int>", "<func>" etc. or nil if they are nil
+//   - Complex numbers become their string representation e.g. "(1+2i)"
+//   - Errors become their message, rather than the {} of their unexported fields
+//   - NaN and ±Inf become "NaN", "+Inf" and "-Inf" if the limits require it
+//   - Maps with unsupported key types get string keys if the limits require it
+//
+// Otherwise values that encode themselves (with a MarshalJSON, MarshalYAML or
+// MarshalText method) are left alone, as are struct tags and field order. If there's nothing
+// to replace, value is returned as is.
+func Normalise(value any, limits Limits) any {
+	n := normaliser{limits: limits, visiting: make(map[uintptr]bool)}
+
+	v, changed := n.normalise(reflect.ValueOf(value))
+	if !changed {
+		return value
+	}
+
+	return v.Interface()
+}
+
+// normaliser holds the state of a single call to [Normalise].
+type normaliser struct {
+	visiting map[uintptr]bool // Pointers and maps on the current path, to avoid cycling forever
+	limits   Limits
+}
+
+// normalise returns the normalised version of v, reporting whether it had to be
+// changed at all.
+func (n normaliser) normalise(v reflect.Value) (reflect.Value, bool) {
+	if v.IsValid() && v.Kind() == reflect.Pointer && n.visiting[v.Pointer()] {
+		return v, false
+	}
+
+	// A Snapshotter is replaced by its snapshot, which isn't asked for its own
+	// snapshot in turn in case it returns another Snapshotter
+	if snapshot, ok := SnapshotOf(v); ok {
+		replaced, _ := n.replace(snapshot)
+
+		return replaced, true
+	}
+
+	return n.replace(v)
+}
+
+// replace returns v with anything that can't be encoded replaced, reporting
+// whether anything was.
+func (n normaliser) replace(v reflect.Value) (reflect.Value, bool) {
+	if !v.IsValid() || marshals(v.Type()) {
+		return v, false
+	}
+
+	if err, ok := asError(v); ok {
+		return reflect.ValueOf(err.Error()), true
+	}
+
+	switch v.Kind() {
+	case reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		if v.IsNil() {
+			return reflect.Zero(reflect.TypeFor[any]()), true
+		}
+
+		return reflect.ValueOf(placeholder(v.Type())), true
+	case reflect.Complex64, reflect.Complex128:
+		return reflect.ValueOf(strconv.FormatComplex(v.Complex(), 'g', -1, v.Type().Bits())), true
+	case reflect.Float32, reflect.Float64:
+		if !n.limits.NonFiniteFloats || !math.IsNaN(v.Float()) && !math.IsInf(v.Float(), 0) {
+			return v, false
+		}
+
+		return reflect.ValueOf(strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits())), true
+	case reflect.Interface:
+		return n.normalise(v.Elem())
+	case reflect.Pointer:
+		return n.pointer(v)
+	case reflect.Struct:
+		return n.structure(v)
+	case reflect.Slice, reflect.Array:
+		return n.sequence(v)
+	case reflect.Map:
+		return n.mapping(v)
+	default:
+		return v, false
+	}
+}
+
+// pointer normalises the value pointed to by v, the encoders follow pointers
+// anyway so if it's changed the new value is returned directly.
+func (n normaliser) pointer(v reflect.Value) (reflect.Value, bool) {
+	if v.IsNil() || n.visiting[v.Pointer()] {
+		return v, false
+	}
+
+	n.visiting[v.Pointer()] = true
+	defer delete(n.visiting, v.Pointer())
+
+	elem, changed := n.normalise(v.Elem())
+	if !changed {
+		return v, false
+	}
+
+	return elem, true
+}
+
+// structure normalises a struct, rebuilding it as a new struct type with the same
+// exported fields and tags if any of them had to change.
+//
+// Unexported fields are dropped, the encoders ignore them anyway. The exception
+// is embedded structs, whose exported fields encoding/json promotes, so these are
+// always rebuilt with their fields promoted, see [normaliser.fields].
+func (n normaliser) structure(v reflect.Value) (reflect.Value, bool) {
+	fields, values, changed := n.fields(Addressable(v), nil)
+	if !changed {
+		return v, false
+	}
+
+	typ, ok := structOf(fields)
+	if !ok {
+		return v, false
+	}
+
+	rebuilt := reflect.New(typ).Elem()
+	for i, value := range values {
+		if value.IsValid() {
+			rebuilt.Field(i).Set(value)
+		}
+	}
+
+	return rebuilt, true
+}
+
+// structOf returns the struct type with fields, reporting whether it could be
+// built at all.
+//
+// [reflect.StructOf] has a few restrictions, e.g. embedded fields with methods,
+// if we fall foul of one the struct is left as is for the encoder to report what
+// it can't handle instead.
+func structOf(fields []reflect.StructField) (typ reflect.Type, ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+
+	return reflect.StructOf(fields), true
+}
+
+// fields returns the fields of the rebuilt version of the struct v and their
+// normalised values, reporting whether any had to change.
+//
+// The exported fields of embedded unexported structs are promoted in their place,
+// unless v (or the struct it's promoted into, whose field names are in outer)
+// already has a field of the same name. The shallower field wins, as it does in
+// encoding/json.
+func (n normaliser) fields(v reflect.Value, outer map[string]bool) ([]reflect.StructField, []reflect.Value, bool) {
+	typ := v.Type()
+
+	names := make(map[string]bool, len(outer)+typ.NumField())
+	for name := range outer {
+		names[name] = true
+	}
+
+	for i := range typ.NumField() {
+		if field := typ.Field(i); field.IsExported() {
+			names[field.Name] = true
+		}
+	}
+
+	var (
+		fields  []reflect.StructField
+		values  []reflect.Value
+		changed bool
+	)
+
+	for i := range typ.NumField() {
+		field := typ.Field(i)
+
+		if embedded, ok := promoted(v.Field(i), field); ok {
+			changed = true
+
+			if !embedded.IsValid() {
+				continue
+			}
+
+			promotedFields, promotedValues, _ := n.fields(embedded, names)
+			for j, promotedField := range promotedFields {
+				// Two embedded structs may have a field of the same name, the first wins
+				if slices.ContainsFunc(fields, func(f reflect.StructField) bool { return f.Name == promotedField.Name }) {
+					continue
+				}
+
+				fields = append(fields, promotedField)
+				values = append(values, promotedValues[j])
+			}
+
+			continue
+		}
+
+		if !field.IsExported() || outer[field.Name] {
+			continue
+		}
+
+		value, fieldChanged := n.normalise(v.Field(i))
+		if fieldChanged {
+			changed = true
+
+			// Embedded structs keep their (new) type so they're still inlined, anything
+			// else can be any type at all now
+			switch {
+			case field.Anonymous && value.Kind() == reflect.Struct:
+				field.Type = value.Type()
+			default:
+				field.Type = reflect.TypeFor[any]()
+				field.Anonymous = false
+			}
+		}
+
+		field.Index = nil
+		field.Offset = 0
+		fields = append(fields, field)
+		values = append(values, value)
+	}
+
+	return fields, values, changed
+}
+
+// promoted returns the struct embedded in an unexported field whose exported
+// fields are promoted, reporting whether field is one at all.
+//
+// The returned value is invalid if it's a nil pointer, there's nothing to promote.
+func promoted(v reflect.Value, field reflect.StructField) (reflect.Value, bool) {
+	if !field.Anonymous || field.IsExported() {
+		return reflect.Value{}, false
+	}
+
+	typ := field.Type
+	if typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	if typ.Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+
+	v, ok := Accessible(v)
+	if !ok {
+		return reflect.Value{}, false
+	}
+
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return reflect.Value{}, true
+		}
+
+		v = v.Elem()
+	}
+
+	return v, true
+}
+
+// sequence normalises a slice or array, rebuilding it as an []any if any of its
+// elements had to change.
+func (n normaliser) sequence(v reflect.Value) (reflect.Value, bool) {
+	if v.Kind() == reflect.Slice && v.IsNil() {
+		return v, false
+	}
+
+	elems := make([]any, v.Len())
+	changed := false
+
+	for i := range v.Len() {
+		elem, elemChanged := n.normalise(v.Index(i))
+		changed = changed || elemChanged
+
+		if elem.IsValid() {
+			elems[i] = elem.Interface()
+		}
+	}
+
+	if !changed {
+		return v, false
+	}
+
+	return reflect.ValueOf(elems), true
+}
+
+// mapping normalises a map, rebuilding it with any values if any of them had
+// to change, and with string keys if its key type isn't supported.
+func (n normaliser) mapping(v reflect.Value) (reflect.Value, bool) {
+	if v.IsNil() || n.visiting[v.Pointer()] {
+		return v, false
+	}
+
+	n.visiting[v.Pointer()] = true
+	defer delete(n.visiting, v.Pointer())
+
+	stringKeys := !validKey(v.Type().Key(), n.limits)
+	changed := stringKeys
+
+	keys := make([]reflect.Value, 0, v.Len())
+	values := make([]reflect.Value, 0, v.Len())
+
+	for iter := v.MapRange(); iter.Next(); {
+		value, valueChanged := n.normalise(iter.Value())
+		changed = changed || valueChanged
+
+		keys = append(keys, iter.Key())
+		values = append(values, value)
+	}
+
+	if !changed {
+		return v, false
+	}
+
+	keyType := v.Type().Key()
+	if stringKeys {
+		keyType = reflect.TypeFor[string]()
+	}
+
+	rebuilt := reflect.MakeMapWithSize(reflect.MapOf(keyType, reflect.TypeFor[any]()), len(keys))
+
+	for i, key := range keys {
+		if stringKeys {
+			key = reflect.ValueOf(fmt.Sprint(key.Interface()))
+		}
+
+		value := values[i]
+		if !value.IsValid() {
+			value = reflect.Zero(reflect.TypeFor[any]())
+		}
+
+		rebuilt.SetMapIndex(key, value)
+	}
+
+	return rebuilt, true
+}
+
+// marshals reports whether values of typ encode themselves.
+func marshals(typ reflect.Type) bool {
+	for _, t := range []reflect.Type{typ, reflect.PointerTo(typ)} {
+		if t.Implements(reflect.TypeFor[json.Marshaler]()) ||
+			t.Implements(reflect.TypeFor[yaml.Marshaler]()) ||
+			t.Implements(reflect.TypeFor[encoding.TextMarshaler]()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// asError returns the error held in v, reporting whether v holds a non-nil error.
+func asError(v reflect.Value) (error, bool) {
+	if !v.CanInterface() || !v.Type().Implements(reflect.TypeFor[error]()) {
+		return nil, false
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Map, reflect.Slice, reflect.Chan, reflect.Func:
+		if v.IsNil() {
+			return nil, false
+		}
+	}
+
+	err, ok := v.Interface().(error)
+
+	return err, ok
+}
+
+// validKey reports whether typ is a map key type supported within limits.
+func validKey(typ reflect.Type, limits Limits) bool {
+	switch typ.Kind() {
+	case reflect.String:
+		return true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if !limits.StringKeys {
+			return true
+		}
+	}
+
+	if limits.MapKeys || limits.StringKeys {
+		return typ.Implements(reflect.TypeFor[encoding.TextMarshaler]())
+	}
+
+	return true
+}
+
+// placeholder returns the placeholder for a non-nil value of typ, which can't be
+// encoded e.g. "<chan int>".
+//
+// Func signatures are left out, they're long and say nothing about the value.
+func placeholder(typ reflect.Type) string {
+	if typ.Kind() == reflect.Func {
+		return "<func>"
+	}
+
+	return "<" + strings.ReplaceAll(typ.String(), "interface {}", "any") + ">"
+}
@@ -0,0 +1,222 @@
+package format_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.followtheprocess.codes/snapshot/internal/format"
+	"go.followtheprocess.codes/test"
+)
+
+type Inner struct {
+	Done chan struct{} `json:"done"`
+	ID   int           `json:"id"`
+}
+
+type inner struct {
+	B string `json:"b"`
+	A int    `json:"a"`
+}
+
+type node struct {
+	Next *node  `json:"next"`
+	Name string `json:"name"`
+}
+
+// token is a Snapshotter hiding its value.
+type token struct {
+	value string
+}
+
+func (t token) Snapshot() any {
+	return "<token>"
+}
+
+// same is a Snapshotter that snapshots as itself.
+type same struct {
+	N int
+}
+
+func (s same) Snapshot() any {
+	return s
+}
+
+// broken is a Snapshotter with a bug.
+type broken struct{}
+
+func (b broken) Snapshot() any {
+	panic("boom")
+}
+
+func TestNormalise(t *testing.T) {
+	limits := format.Limits{NonFiniteFloats: true, MapKeys: true}
+
+	tests := []struct {
+		value any
+		name  string
+		want  string // The normalised value encoded as JSON
+	}{
+		{
+			name:  "nil",
+			value: nil,
+			want:  `null`,
+		},
+		{
+			name:  "error",
+			value: errors.New("boom"),
+			want:  `"boom"`,
+		},
+		{
+			name:  "nil error",
+			value: struct{ Err error }{},
+			want:  `{"Err":null}`,
+		},
+		{
+			name:  "complex",
+			value: []complex128{1 + 2i},
+			want:  `["(1+2i)"]`,
+		},
+		{
+			name:  "infinities",
+			value: map[string]float64{"neg": math.Inf(-1), "pos": math.Inf(1), "ok": 1.5},
+			want:  `{"neg":"-Inf","ok":1.5,"pos":"+Inf"}`,
+		},
+		{
+			name:  "pointer",
+			value: &struct{ F func(int) error }{F: func(int) error { return nil }},
+			want:  `{"F":"<func>"}`,
+		},
+		{
+			// Embedded structs must still be inlined once rebuilt
+			name: "embedded",
+			value: struct {
+				Inner
+
+				Name string `json:"name"`
+			}{Inner: Inner{ID: 1, Done: make(chan struct{})}, Name: "outer"},
+			want: `{"done":"<chan struct {}>","id":1,"name":"outer"}`,
+		},
+		{
+			// encoding/json promotes the fields of embedded unexported structs too, so
+			// they mustn't be dropped along with the other unexported fields
+			name: "embedded unexported",
+			value: struct {
+				inner
+
+				F func()
+			}{inner: inner{A: 1, B: "x"}, F: func() {}},
+			want: `{"b":"x","a":1,"F":"<func>"}`,
+		},
+		{
+			// The outer field wins, as it does in encoding/json
+			name: "embedded unexported pointer shadowed",
+			value: struct {
+				*inner
+
+				A string `json:"a"`
+			}{inner: &inner{A: 1, B: "x"}, A: "a"},
+			want: `{"b":"x","a":"a"}`,
+		},
+		{
+			name: "embedded unexported nil",
+			value: struct {
+				*inner
+
+				F func()
+			}{},
+			want: `{"F":null}`,
+		},
+		{
+			name:  "array",
+			value: [2]func(){nil, func() {}},
+			want:  `[null,"<func>"]`,
+		},
+		{
+			name:  "struct keys",
+			value: map[struct{ A int }]string{{A: 1}: "one"},
+			want:  `{"{1}":"one"}`,
+		},
+		{
+			name:  "snapshotter",
+			value: []any{token{value: "secret"}},
+			want:  `["<token>"]`,
+		},
+		{
+			// Mustn't ask the snapshot for its snapshot forever
+			name:  "snapshotter returning itself",
+			value: same{N: 1},
+			want:  `{"N":1}`,
+		},
+		{
+			// Values that marshal themselves are left to do so
+			name:  "marshaler",
+			value: map[string]time.Time{"epoch": time.Unix(0, 0).UTC()},
+			want:  `{"epoch":"1970-01-01T00:00:00Z"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+
+			encoder := json.NewEncoder(buf)
+			encoder.SetEscapeHTML(false)
+
+			test.Ok(t, encoder.Encode(format.Normalise(tt.value, limits)))
+			test.Equal(t, strings.TrimSpace(buf.String()), tt.want)
+		})
+	}
+}
+
+func TestNormaliseUnchanged(t *testing.T) {
+	// Nothing to replace so the very same value comes back, not a copy
+	value := map[string][]int{"a": {1, 2}}
+
+	got := format.Normalise(value, format.Limits{NonFiniteFloats: true, MapKeys: true})
+	test.Equal(t, reflect.ValueOf(got).Pointer(), reflect.ValueOf(value).Pointer())
+
+	// Without the limits, things the encoder can handle are left alone
+	floats := []float64{math.NaN()}
+	got = format.Normalise(floats, format.Limits{})
+	test.Equal(t, reflect.ValueOf(got).Pointer(), reflect.ValueOf(floats).Pointer())
+}
+
+func TestNormaliseCycle(t *testing.T) {
+	// A cycle must not send the normaliser round forever, it's left for the
+	// encoder to report
+	cycle := &node{Name: "a"}
+	cycle.Next = cycle
+
+	got := format.Normalise(cycle, format.Limits{})
+	test.Equal(t, got, any(cycle))
+}
+
+func TestNormaliseUnbuildable(t *testing.T) {
+	// reflect.StructOf can't build a struct embedding a pointer with methods, so
+	// it's left as is for the encoder to report what it can't handle
+	value := struct {
+		*bytes.Buffer
+
+		F func()
+	}{Buffer: &bytes.Buffer{}, F: func() {}}
+
+	got := format.Normalise(value, format.Limits{})
+	test.Equal(t, reflect.TypeOf(got), reflect.TypeOf(value))
+}
+
+func TestNormaliseSnapshotPanics(t *testing.T) {
+	// A bug in a Snapshot method must fail the test, not be swallowed along with
+	// the reflect.StructOf restrictions
+	defer func() {
+		test.Equal(t, recover(), any("boom"))
+	}()
+
+	format.Normalise(struct{ B broken }{}, format.Limits{})
+	t.Fatal("Normalise should have panicked")
+}
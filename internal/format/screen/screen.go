@@ -0,0 +1,305 @@
+// Package screen provides a formatter that plays terminal output through a small
+// terminal emulator and snapshots the screen it leaves behind, for output like
+// progress bars and spinners that redraws itself.
+package screen
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"go.followtheprocess.codes/snapshot/internal/format"
+)
+
+const (
+	// defaultWidth is the width of the screen if the config doesn't set one.
+	defaultWidth = 80
+
+	// defaultHeight is the height of the screen if the config doesn't set one.
+	defaultHeight = 24
+
+	// tabWidth is the distance between tab stops.
+	tabWidth = 8
+)
+
+// Config controls the size of the screen, the zero value is 80 columns by 24 rows.
+type Config struct {
+	Width  int // Number of columns, 0 means 80
+	Height int // Number of rows, 0 means 24
+}
+
+// Formatter implements [snapshot.Formatter] and returns a snapshot format of
+// the screen a terminal would show.
+type Formatter struct {
+	config Config
+}
+
+// NewFormatter returns a new screen Formatter, emulating a terminal of the
+// size in config.
+func NewFormatter(config Config) Formatter {
+	if config.Width <= 0 {
+		config.Width = defaultWidth
+	}
+
+	if config.Height <= 0 {
+		config.Height = defaultHeight
+	}
+
+	return Formatter{config: config}
+}
+
+// Ext returns the file extension for a screen snapshot.
+func (f Formatter) Ext() string {
+	return ".snap.txt"
+}
+
+// Format returns a snapshot of the screen a terminal would show after the value,
+// which must be a string or []byte, was written to it.
+//
+// Carriage returns, backspaces, tabs, moving the cursor and erasing the line or
+// screen are all emulated, text wraps at the edge of the screen and scrolls off
+// the top of it, and a newline moves to the start of the next line. Colours,
+// styles and any other escape sequences are ignored.
+//
+// Each row of the screen is written as a line with trailing spaces removed, and
+// blank rows at the bottom of the screen are left out.
+func (f Formatter) Format(value any) ([]byte, error) {
+	var output []byte
+
+	switch value := format.Snapshot(value).(type) {
+	case string:
+		output = []byte(value)
+	case []byte:
+		output = value
+	default:
+		return nil, fmt.Errorf("a screen snapshot must be a string or []byte, got %T", value)
+	}
+
+	t := newTerminal(f.config.Width, f.config.Height)
+	t.write(output)
+
+	return t.screen(), nil
+}
+
+// terminal is the state of an emulated terminal.
+type terminal struct {
+	cells  [][]rune // The screen, indexed by row then column
+	row    int      // Row of the cursor
+	col    int      // Column of the cursor, width if it's past the last one
+	saved  [2]int   // Row and column saved by ESC 7 or CSI s
+	width  int      // Number of columns
+	height int      // Number of rows
+}
+
+// newTerminal returns a terminal with a blank screen of the given size.
+func newTerminal(width, height int) *terminal {
+	t := &terminal{width: width, height: height, cells: make([][]rune, height)}
+	for row := range t.cells {
+		t.cells[row] = blank(width)
+	}
+
+	return t
+}
+
+// write plays output through the terminal.
+func (t *terminal) write(output []byte) {
+	for len(output) > 0 {
+		if escape, ok := format.ParseEscape(output); ok {
+			t.escape(escape)
+			output = output[escape.Len:]
+
+			continue
+		}
+
+		r, size := utf8.DecodeRune(output)
+		output = output[size:]
+
+		switch r {
+		case '\n':
+			t.col = 0
+			t.down()
+		case '\r':
+			t.col = 0
+		case '\b':
+			t.col = max(min(t.col, t.width-1)-1, 0)
+		case '\t':
+			t.col = min((t.col/tabWidth+1)*tabWidth, t.width-1)
+		default:
+			if unicode.IsPrint(r) {
+				t.print(r)
+			}
+		}
+	}
+}
+
+// print writes r at the cursor and moves it on, wrapping to the next line first
+// if the last one filled the line.
+func (t *terminal) print(r rune) {
+	if t.col >= t.width {
+		t.col = 0
+		t.down()
+	}
+
+	t.cells[t.row][t.col] = r
+	t.col++
+}
+
+// down moves the cursor down a line, scrolling the screen up if it's on the last.
+func (t *terminal) down() {
+	if t.row < t.height-1 {
+		t.row++
+
+		return
+	}
+
+	t.cells = append(t.cells[1:], blank(t.width))
+}
+
+// move moves the cursor to row and col, keeping it on the screen.
+func (t *terminal) move(row, col int) {
+	t.row = max(0, min(row, t.height-1))
+	t.col = max(0, min(col, t.width-1))
+}
+
+// escape carries out an escape sequence, ignoring any that don't affect the screen.
+func (t *terminal) escape(escape format.Escape) {
+	switch escape.Intro {
+	case '[':
+		if escape.Final != 0 {
+			t.control(escape.Params, escape.Final)
+		}
+	case '7':
+		t.saved = [2]int{t.row, t.col}
+	case '8':
+		t.move(t.saved[0], t.saved[1])
+	}
+}
+
+// control carries out the CSI sequence with the given parameters and final byte.
+func (t *terminal) control(params string, final byte) {
+	// Private sequences e.g. hiding the cursor don't change the screen
+	if strings.HasPrefix(params, "?") {
+		return
+	}
+
+	args := strings.Split(params, ";")
+
+	// arg returns the i'th argument, or def if it's missing or 0
+	arg := func(i, def int) int {
+		if i >= len(args) {
+			return def
+		}
+
+		n, err := strconv.Atoi(args[i])
+		if err != nil || n == 0 {
+			return def
+		}
+
+		return n
+	}
+
+	switch final {
+	case 'A': // Up
+		t.move(t.row-arg(0, 1), t.col)
+	case 'B': // Down
+		t.move(t.row+arg(0, 1), t.col)
+	case 'C': // Forward
+		t.move(t.row, t.col+arg(0, 1))
+	case 'D': // Back
+		t.move(t.row, min(t.col, t.width-1)-arg(0, 1))
+	case 'E': // Start of a following line
+		t.move(t.row+arg(0, 1), 0)
+	case 'F': // Start of a preceding line
+		t.move(t.row-arg(0, 1), 0)
+	case 'G': // Column
+		t.move(t.row, arg(0, 1)-1)
+	case 'H', 'f': // Row and column
+		t.move(arg(0, 1)-1, arg(1, 1)-1)
+	case 'J': // Erase in display
+		t.eraseDisplay(arg(0, 0))
+	case 'K': // Erase in line
+		t.eraseLine(arg(0, 0))
+	case 's': // Save the cursor
+		t.saved = [2]int{t.row, t.col}
+	case 'u': // Restore the cursor
+		t.move(t.saved[0], t.saved[1])
+	}
+}
+
+// eraseDisplay erases from the cursor to the end of the screen (mode 0), from the
+// start of the screen to the cursor (mode 1) or the whole screen (mode 2 or 3).
+func (t *terminal) eraseDisplay(mode int) {
+	switch mode {
+	case 0:
+		t.eraseLine(0)
+
+		for row := t.row + 1; row < t.height; row++ {
+			t.cells[row] = blank(t.width)
+		}
+	case 1:
+		t.eraseLine(1)
+
+		for row := range t.row {
+			t.cells[row] = blank(t.width)
+		}
+	case 2, 3:
+		for row := range t.cells {
+			t.cells[row] = blank(t.width)
+		}
+	}
+}
+
+// eraseLine erases from the cursor to the end of the line (mode 0), from the start
+// of the line to the cursor (mode 1) or the whole line (mode 2).
+func (t *terminal) eraseLine(mode int) {
+	line := t.cells[t.row]
+	col := min(t.col, t.width-1)
+
+	switch mode {
+	case 0:
+		fill(line[col:])
+	case 1:
+		fill(line[:col+1])
+	case 2:
+		fill(line)
+	}
+}
+
+// screen returns the contents of the screen, a line per row with trailing spaces
+// and blank rows at the bottom removed.
+func (t *terminal) screen() []byte {
+	lines := make([]string, 0, t.height)
+	for _, row := range t.cells {
+		lines = append(lines, strings.TrimRight(string(row), " "))
+	}
+
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	buf := &bytes.Buffer{}
+	for _, line := range lines {
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+
+	return buf.Bytes()
+}
+
+// blank returns a row of width spaces.
+func blank(width int) []rune {
+	row := make([]rune, width)
+	fill(row)
+
+	return row
+}
+
+// fill sets every cell in cells to a space.
+func fill(cells []rune) {
+	for i := range cells {
+		cells[i] = ' '
+	}
+}
@@ -0,0 +1,68 @@
+package screen_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.followtheprocess.codes/snapshot/internal/format/screen"
+	"go.followtheprocess.codes/test"
+)
+
+func TestFormatter(t *testing.T) {
+	tests := []struct {
+		value  any
+		name   string
+		config screen.Config
+	}{
+		{
+			name:  "progress",
+			value: "Downloading  0%\rDownloading 50%\rDownloading 100%\ndone\n",
+		},
+		{
+			name:  "spinner",
+			value: []byte("⠋ working\r\x1b[2K⠙ working\r\x1b[2K✓ finished\n"),
+		},
+		{
+			name:  "cursor",
+			value: "one\ntwo\nthree\n\x1b[2A\x1b[2KTWO\x1b[3;3H!",
+		},
+		{
+			name:  "wrap_scroll",
+			value: "0123456789abc\nline 2\nline 3\nline 4",
+			config: screen.Config{
+				Width:  10,
+				Height: 4,
+			},
+		},
+		{
+			name:  "clear_screen",
+			value: "old stuff\nmore\x1b[2J\x1b[Hnew\tcol\x1b7\nx\x1b8!",
+		},
+		{
+			name:  "ignored",
+			value: "\x1b]0;title\x07\x1b[?25l\x1b[1;32mok\x1b(B\x1b[m\b\bOK\x1b[?25h\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test.ColorEnabled(os.Getenv("CI") == "")
+
+			path := filepath.Join("testdata", "TestFormatter", tt.name+".snap.txt")
+
+			want, err := os.ReadFile(path)
+			test.Ok(t, err)
+
+			got, err := screen.NewFormatter(tt.config).Format(tt.value)
+			test.Ok(t, err)
+
+			test.DiffBytes(t, got, want)
+		})
+	}
+}
+
+func TestFormatterError(t *testing.T) {
+	_, err := screen.NewFormatter(screen.Config{}).Format(42)
+	test.Err(t, err)
+}
@@ -0,0 +1,53 @@
+package format
+
+import "reflect"
+
+// Snapshotter is implemented by types that control their own snapshot
+// representation, see snapshot.Snapshotter.
+type Snapshotter interface {
+	// Snapshot returns the value to snapshot in place of the receiver.
+	Snapshot() any
+}
+
+// Snapshot returns the value to snapshot in place of value, which is the result
+// of its Snapshot method if it's a [Snapshotter] and value itself otherwise.
+//
+// Only value itself is checked, not anything nested inside it.
+func Snapshot(value any) any {
+	if snapshot, ok := SnapshotOf(reflect.ValueOf(value)); ok {
+		return snapshot.Interface()
+	}
+
+	return value
+}
+
+// SnapshotOf returns the result of calling v's Snapshot method, reporting whether
+// v is a [Snapshotter] at all.
+//
+// A nil pointer or interface is never a Snapshotter, the method would likely panic.
+// Nor is one whose snapshot is of its own type, asking that for its snapshot in
+// turn would never end.
+func SnapshotOf(v reflect.Value) (reflect.Value, bool) {
+	if !v.IsValid() || !v.CanInterface() {
+		return reflect.Value{}, false
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return reflect.Value{}, false
+		}
+	}
+
+	snapshotter, ok := v.Interface().(Snapshotter)
+	if !ok {
+		return reflect.Value{}, false
+	}
+
+	snapshot := reflect.ValueOf(snapshotter.Snapshot())
+	if snapshot.IsValid() && snapshot.Type() == v.Type() {
+		return reflect.Value{}, false
+	}
+
+	return snapshot, true
+}
@@ -0,0 +1,379 @@
+// Package table provides a formatter that renders slices of structs or maps as
+// tables, one row per line, for snapshots.
+package table
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/csv"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"go.followtheprocess.codes/snapshot/internal/format"
+)
+
+const (
+	// defaultTag is the struct tag naming columns if the config doesn't set one.
+	defaultTag = "table"
+
+	// padding is the number of spaces between columns in a text table.
+	padding = 2
+)
+
+// Config controls how tables are rendered, the zero value is an aligned text table
+// with columns named by the "table" struct tag.
+type Config struct {
+	Tag string // Struct tag naming columns e.g. "json", "" means "table"
+	CSV bool   // Render CSV rather than an aligned text table
+}
+
+// Formatter implements [snapshot.Formatter] and returns a tabular
+// snapshot format.
+type Formatter struct {
+	config Config
+}
+
+// NewFormatter returns a new table Formatter, rendering tables according
+// to config.
+func NewFormatter(config Config) Formatter {
+	return Formatter{config: config}
+}
+
+// Ext returns the file extension for a table snapshot.
+func (f Formatter) Ext() string {
+	if f.config.CSV {
+		return ".snap.csv"
+	}
+
+	return ".snap.txt"
+}
+
+// Format returns a table snapshot of the value, which must be a slice or array of
+// structs or maps (or pointers to them), one row for each element.
+//
+// For structs the columns are the exported fields in the order they are declared,
+// named by the configured struct tag if they have one (a tag of "-" leaves the
+// field out) or the field name if not. Embedded structs' fields are promoted just
+// as encoding/json does. For maps the columns are the keys of every row, sorted,
+// so the order of the rows doesn't change the header. If the rows are a mix of
+// structs and maps, the columns are the union of them all in the order they're
+// first seen. A row without a column has an empty cell.
+//
+// Cells are written using a Snapshot, MarshalText, Error or String method if the
+// value has one. In a text table, newlines and tabs are escaped so each row stays
+// on one line.
+func (f Formatter) Format(value any) ([]byte, error) {
+	rows := reflect.ValueOf(format.Snapshot(value))
+	if rows.Kind() != reflect.Slice && rows.Kind() != reflect.Array {
+		return nil, fmt.Errorf("a table snapshot must be a slice of structs or maps, got %T", value)
+	}
+
+	tag := f.config.Tag
+	if tag == "" {
+		tag = defaultTag
+	}
+
+	t := table{tag: tag, index: make(map[string]int)}
+
+	// If the element type is a struct, it has columns even if there are no rows
+	if elem := indirectType(rows.Type().Elem()); elem.Kind() == reflect.Struct {
+		t.structColumns(elem)
+	}
+
+	for i := range rows.Len() {
+		if err := t.add(rows.Index(i)); err != nil {
+			return nil, fmt.Errorf("row %d: %w", i, err)
+		}
+	}
+
+	// Struct fields have an order of their own, map keys don't
+	if !t.structs {
+		slices.Sort(t.columns)
+	}
+
+	if f.config.CSV {
+		return t.csv()
+	}
+
+	return t.text()
+}
+
+// table accumulates the columns and rows of a table.
+type table struct {
+	index   map[string]int      // Column name to position
+	tag     string              // Struct tag naming columns
+	columns []string            // Column names in order
+	rows    []map[string]string // Cells of each row by column name
+	structs bool                // Whether any columns are struct fields
+}
+
+// add adds a row to the table for v, adding any columns it has that the table
+// doesn't have yet.
+func (t *table) add(v reflect.Value) error {
+	if snapshot, ok := format.SnapshotOf(v); ok {
+		v = snapshot
+	}
+
+	v = indirect(v)
+
+	row := make(map[string]string)
+
+	switch v.Kind() {
+	case reflect.Invalid:
+		// A nil row, which has no cells
+	case reflect.Struct:
+		t.structColumns(v.Type())
+		t.structCells(v, row)
+	case reflect.Map:
+		keys := make([]string, 0, v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			key := cell(iter.Key())
+			keys = append(keys, key)
+			row[key] = cell(iter.Value())
+		}
+
+		// Sorted so that columns are first seen in the same order every time
+		slices.Sort(keys)
+
+		for _, key := range keys {
+			t.column(key)
+		}
+	default:
+		return fmt.Errorf("a table row must be a struct or map, got %s", v.Type())
+	}
+
+	t.rows = append(t.rows, row)
+
+	return nil
+}
+
+// structColumns adds the columns for the fields of the struct type typ.
+func (t *table) structColumns(typ reflect.Type) {
+	t.structs = true
+
+	for _, field := range reflect.VisibleFields(typ) {
+		if name, ok := t.name(field); ok {
+			t.column(name)
+		}
+	}
+}
+
+// structCells fills row with the cells for the fields of the struct v.
+func (t *table) structCells(v reflect.Value, row map[string]string) {
+	for _, field := range reflect.VisibleFields(v.Type()) {
+		name, ok := t.name(field)
+		if !ok {
+			continue
+		}
+
+		// A field promoted through a nil embedded pointer has no value
+		value, err := v.FieldByIndexErr(field.Index)
+		if err != nil {
+			continue
+		}
+
+		row[name] = cell(value)
+	}
+}
+
+// name returns the column name for a struct field, reporting whether it's a
+// column at all.
+//
+// Unexported fields, embedded structs (whose fields are promoted instead) and
+// fields tagged "-" aren't columns.
+func (t *table) name(field reflect.StructField) (string, bool) {
+	if !field.IsExported() || field.Anonymous && indirectType(field.Type).Kind() == reflect.Struct {
+		return "", false
+	}
+
+	name, _, _ := strings.Cut(field.Tag.Get(t.tag), ",")
+
+	switch name {
+	case "-":
+		return "", false
+	case "":
+		return field.Name, true
+	default:
+		return name, true
+	}
+}
+
+// column adds a column to the table, if it doesn't already have it.
+func (t *table) column(name string) {
+	if _, ok := t.index[name]; ok {
+		return
+	}
+
+	t.index[name] = len(t.columns)
+	t.columns = append(t.columns, name)
+}
+
+// text renders the table as aligned text, a header line and then a line per row.
+func (t *table) text() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	writer := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+
+	line := func(cells []string) {
+		for i, cell := range cells {
+			cells[i] = escape(cell)
+		}
+
+		fmt.Fprintln(writer, strings.Join(cells, "\t"))
+	}
+
+	line(slices.Clone(t.columns))
+
+	for _, row := range t.rows {
+		line(t.cells(row))
+	}
+
+	if err := writer.Flush(); err != nil {
+		return nil, fmt.Errorf("could not write table: %w", err)
+	}
+
+	// Empty cells at the end of a line are still padded
+	return trimLines(buf.Bytes()), nil
+}
+
+// csv renders the table as CSV, a header record and then a record per row.
+func (t *table) csv() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	writer := csv.NewWriter(buf)
+
+	records := [][]string{t.columns}
+	for _, row := range t.rows {
+		records = append(records, t.cells(row))
+	}
+
+	if err := writer.WriteAll(records); err != nil {
+		return nil, fmt.Errorf("could not write CSV: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// cells returns the cells of row in column order.
+func (t *table) cells(row map[string]string) []string {
+	cells := make([]string, len(t.columns))
+	for i, column := range t.columns {
+		cells[i] = row[column]
+	}
+
+	return cells
+}
+
+// cell returns the contents of the cell for v.
+func cell(v reflect.Value) string {
+	if snapshot, ok := format.SnapshotOf(v); ok {
+		v = snapshot
+	}
+
+	// Methods may be on the pointer or the value it points to
+	if text, ok := describe(v); ok {
+		return text
+	}
+
+	v = indirect(v)
+	if !v.IsValid() {
+		return ""
+	}
+
+	if text, ok := describe(v); ok {
+		return text
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits())
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return string(v.Bytes())
+		}
+
+		return fmt.Sprint(v.Interface())
+	default:
+		if !v.CanInterface() {
+			return ""
+		}
+
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+// describe returns v as described by its MarshalText, Error or String method,
+// reporting whether it has one.
+func describe(v reflect.Value) (string, bool) {
+	if !v.IsValid() || !v.CanInterface() {
+		return "", false
+	}
+
+	if (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) && v.IsNil() {
+		return "", false
+	}
+
+	switch value := v.Interface().(type) {
+	case encoding.TextMarshaler:
+		text, err := value.MarshalText()
+		if err != nil {
+			return "", false
+		}
+
+		return string(text), true
+	case error:
+		return value.Error(), true
+	case fmt.Stringer:
+		return value.String(), true
+	default:
+		return "", false
+	}
+}
+
+// indirect follows pointers and interfaces until it reaches a value, returning the
+// zero Value if it reaches nil.
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+
+		v = v.Elem()
+	}
+
+	return v
+}
+
+// indirectType follows pointer types until it reaches a non-pointer type.
+func indirectType(typ reflect.Type) reflect.Type {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	return typ
+}
+
+// escape escapes the characters in a text table cell that would break the table.
+func escape(cell string) string {
+	return strings.NewReplacer("\n", `\n`, "\r", `\r`, "\t", `\t`).Replace(cell)
+}
+
+// trimLines removes any trailing whitespace from each line of text.
+func trimLines(text []byte) []byte {
+	lines := bytes.Split(text, []byte("\n"))
+	for i, line := range lines {
+		lines[i] = bytes.TrimRight(line, " ")
+	}
+
+	return bytes.Join(lines, []byte("\n"))
+}
@@ -0,0 +1,134 @@
+package table_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.followtheprocess.codes/snapshot/internal/format/table"
+	"go.followtheprocess.codes/test"
+)
+
+type audit struct {
+	Created time.Time `table:"created"`
+}
+
+type user struct {
+	*audit
+
+	Email    string `table:"email"`
+	Password string `table:"-"`
+	Name     string `json:"full_name" table:"name"`
+	Note     string `table:"note"`
+	Age      int    `table:"age"`
+	internal bool
+}
+
+func TestFormatter(t *testing.T) {
+	created := &audit{Created: time.Date(2024, time.March, 1, 12, 30, 0, 0, time.UTC)}
+
+	users := []user{
+		{Name: "Alice", Email: "alice@example.com", Age: 30, Password: "hunter2", audit: created},
+		{Name: "Bob", Email: "bob@example.com", Age: 4, Note: "two\nlines"},
+	}
+
+	tests := []struct {
+		value  any
+		name   string
+		config table.Config
+	}{
+		{
+			name:  "structs",
+			value: users,
+		},
+		{
+			name:  "empty",
+			value: []user{},
+		},
+		{
+			// Every key is a column, sorted whichever row it's in
+			name: "maps",
+			value: []map[string]any{
+				{"status": "ok", "code": 200},
+				{"code": 404, "error": "not found"},
+			},
+		},
+		{
+			// Rows may differ, the columns are the union of them all
+			name: "mixed",
+			value: []any{
+				struct{ ID int }{ID: 1},
+				map[string]string{"Extra": "yes", "ID": "2"},
+				nil,
+			},
+		},
+		{
+			name:   "tag",
+			value:  []user{{Name: "Alice", Email: "alice@example.com", Age: 30}},
+			config: table.Config{Tag: "json"},
+		},
+		{
+			name:   "csv",
+			value:  users,
+			config: table.Config{CSV: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test.ColorEnabled(os.Getenv("CI") == "")
+
+			formatter := table.NewFormatter(tt.config)
+
+			path := filepath.Join("testdata", "TestFormatter", tt.name+formatter.Ext())
+
+			want, err := os.ReadFile(path)
+			test.Ok(t, err)
+
+			got, err := formatter.Format(tt.value)
+			test.Ok(t, err)
+
+			test.DiffBytes(t, got, want)
+		})
+	}
+}
+
+func TestFormatterErrors(t *testing.T) {
+	tests := []struct {
+		value any
+		name  string
+	}{
+		{name: "not a slice", value: user{Name: "Alice"}},
+		{name: "not rows", value: []int{1, 2, 3}},
+		{name: "nil", value: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := table.NewFormatter(table.Config{}).Format(tt.value)
+			test.Err(t, err)
+		})
+	}
+}
+
+func TestFormatterMapRowOrder(t *testing.T) {
+	// The header of a table of maps mustn't depend on which row a key is first in
+	formatter := table.NewFormatter(table.Config{})
+
+	a, err := formatter.Format([]map[string]int{{"b": 1}, {"a": 2}})
+	test.Ok(t, err)
+
+	b, err := formatter.Format([]map[string]int{{"a": 2}, {"b": 1}})
+	test.Ok(t, err)
+
+	header := func(table []byte) string {
+		line, _, _ := strings.Cut(string(table), "\n")
+
+		return line
+	}
+
+	test.Equal(t, header(a), "a  b")
+	test.Equal(t, header(b), "a  b")
+}
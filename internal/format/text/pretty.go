@@ -0,0 +1,407 @@
+package text
+
+import (
+	"bytes"
+	"cmp"
+	"encoding"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go.followtheprocess.codes/snapshot/internal/format"
+)
+
+// visit identifies a pointer, map or slice being printed, to detect cycles.
+//
+// The type is needed because a struct and its first field share an address, and
+// the length because two slices may share a backing array.
+type visit struct {
+	typ reflect.Type
+	ptr uintptr
+	len int
+}
+
+// printer pretty prints arbitrary Go values using reflection, in a syntax
+// resembling Go composite literals, one field or element per line.
+//
+// The output is deterministic so it's suitable for snapshots: pointers are followed
+// and printed by value rather than address, map keys are sorted, floats are
+// printed in their shortest exact form and cycles are printed as a back-reference
+// to the path at which the value was first seen.
+type printer struct {
+	buf      *bytes.Buffer
+	visiting map[visit]string // Pointers, maps and slices on the current path, to that path
+	depth    int              // Current indentation depth
+}
+
+// pretty returns the pretty printed representation of v.
+func pretty(v reflect.Value) []byte {
+	p := printer{
+		buf:      &bytes.Buffer{},
+		visiting: make(map[visit]string),
+	}
+
+	// Unexported fields can only be made accessible if they're addressable
+	p.print(format.Addressable(v), "")
+
+	return p.buf.Bytes()
+}
+
+// print prints v, found at path.
+func (p *printer) print(v reflect.Value, path string) {
+	if !v.IsValid() {
+		p.buf.WriteString("nil")
+
+		return
+	}
+
+	// Unexported fields are printed too, and their methods called just the same
+	if accessible, ok := format.Accessible(v); ok {
+		v = accessible
+	}
+
+	// Anything that knows how to describe itself gets to, except at the top level
+	// which the Formatter has already dealt with
+	if path != "" {
+		if snapshot, ok := format.SnapshotOf(v); ok {
+			p.print(snapshot, path)
+
+			return
+		}
+
+		if p.method(v) {
+			return
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		p.buf.WriteString(strconv.FormatBool(v.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		p.buf.WriteString(strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		p.buf.WriteString(strconv.FormatUint(v.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		p.float(v.Float(), v.Type().Bits())
+	case reflect.Complex64, reflect.Complex128:
+		p.buf.WriteString(strconv.FormatComplex(v.Complex(), 'g', -1, v.Type().Bits()))
+	case reflect.String:
+		p.buf.WriteString(strconv.Quote(v.String()))
+	case reflect.Interface:
+		p.print(v.Elem(), path)
+	case reflect.Pointer:
+		p.pointer(v, path)
+	case reflect.Struct:
+		p.structure(v, path)
+	case reflect.Slice, reflect.Array:
+		p.sequence(v, path)
+	case reflect.Map:
+		p.mapping(v, path)
+	default:
+		// Chans, funcs and unsafe pointers, all that's stable about them is their type
+		if v.IsNil() {
+			fmt.Fprintf(p.buf, "(%s)(nil)", typeName(v.Type()))
+
+			return
+		}
+
+		fmt.Fprintf(p.buf, "<%s>", typeName(v.Type()))
+	}
+}
+
+// float prints a float in the shortest form that represents it exactly, always
+// with a decimal point or exponent so it can't be mistaken for an integer.
+func (p *printer) float(f float64, bits int) {
+	formatted := strconv.FormatFloat(f, 'g', -1, bits)
+	if !strings.ContainsAny(formatted, ".eIN") {
+		formatted += ".0"
+	}
+
+	p.buf.WriteString(formatted)
+}
+
+// method prints v using its MarshalText, Error or String method if it has one,
+// reporting whether it did.
+func (p *printer) method(v reflect.Value) bool {
+	if !v.CanInterface() || (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) && v.IsNil() {
+		return false
+	}
+
+	switch value := v.Interface().(type) {
+	case encoding.TextMarshaler:
+		text, err := value.MarshalText()
+		if err != nil {
+			return false
+		}
+
+		p.buf.Write(text)
+	case error:
+		p.buf.WriteString(value.Error())
+	case fmt.Stringer:
+		p.buf.WriteString(value.String())
+	default:
+		return false
+	}
+
+	return true
+}
+
+// pointer prints the value v points to, prefixed with &.
+func (p *printer) pointer(v reflect.Value, path string) {
+	if v.IsNil() {
+		fmt.Fprintf(p.buf, "(%s)(nil)", typeName(v.Type()))
+
+		return
+	}
+
+	if p.enter(v, path) {
+		return
+	}
+	defer p.leave(v)
+
+	p.buf.WriteByte('&')
+	p.print(v.Elem(), path)
+}
+
+// structure prints a struct, including its unexported fields.
+func (p *printer) structure(v reflect.Value, path string) {
+	typ := v.Type()
+
+	p.buf.WriteString(typeName(typ))
+
+	if v.NumField() == 0 {
+		p.buf.WriteString("{}")
+
+		return
+	}
+
+	p.open()
+
+	for i := range v.NumField() {
+		p.newline()
+		p.buf.WriteString(typ.Field(i).Name)
+		p.buf.WriteString(": ")
+		p.print(v.Field(i), path+"."+typ.Field(i).Name)
+		p.buf.WriteByte(',')
+	}
+
+	p.close()
+}
+
+// sequence prints a slice or array.
+func (p *printer) sequence(v reflect.Value, path string) {
+	typ := v.Type()
+
+	if v.Kind() == reflect.Slice {
+		if v.IsNil() {
+			fmt.Fprintf(p.buf, "%s(nil)", typeName(typ))
+
+			return
+		}
+
+		if typ.Elem().Kind() == reflect.Uint8 {
+			// Bytes are almost always text of some sort
+			fmt.Fprintf(p.buf, "%s(%s)", typeName(typ), strconv.Quote(string(v.Bytes())))
+
+			return
+		}
+
+		if p.enter(v, path) {
+			return
+		}
+		defer p.leave(v)
+	}
+
+	p.buf.WriteString(typeName(typ))
+
+	if v.Len() == 0 {
+		p.buf.WriteString("{}")
+
+		return
+	}
+
+	p.open()
+
+	for i := range v.Len() {
+		p.newline()
+		p.print(v.Index(i), path+"["+strconv.Itoa(i)+"]")
+		p.buf.WriteByte(',')
+	}
+
+	p.close()
+}
+
+// mapping prints a map, sorted by key.
+func (p *printer) mapping(v reflect.Value, path string) {
+	typ := v.Type()
+
+	if v.IsNil() {
+		fmt.Fprintf(p.buf, "%s(nil)", typeName(typ))
+
+		return
+	}
+
+	if p.enter(v, path) {
+		return
+	}
+	defer p.leave(v)
+
+	p.buf.WriteString(typeName(typ))
+
+	if v.Len() == 0 {
+		p.buf.WriteString("{}")
+
+		return
+	}
+
+	keys := v.MapKeys()
+	slices.SortStableFunc(keys, compare)
+
+	p.open()
+
+	for _, key := range keys {
+		p.newline()
+		p.print(key, path+"[key]")
+		p.buf.WriteString(": ")
+		p.print(v.MapIndex(key), path+"["+keyPath(key)+"]")
+		p.buf.WriteByte(',')
+	}
+
+	p.close()
+}
+
+// enter marks v, found at path, as being printed, reporting whether it already was
+// in which case it's a cycle and a back-reference has been printed instead.
+func (p *printer) enter(v reflect.Value, path string) bool {
+	key := visiting(v)
+
+	if seen, ok := p.visiting[key]; ok {
+		fmt.Fprintf(p.buf, "<cycle: %s>", root(seen))
+
+		return true
+	}
+
+	p.visiting[key] = path
+
+	return false
+}
+
+// leave marks v as no longer being printed.
+func (p *printer) leave(v reflect.Value) {
+	delete(p.visiting, visiting(v))
+}
+
+// visiting returns the key identifying a pointer, map or slice in the set of
+// values being printed.
+func visiting(v reflect.Value) visit {
+	key := visit{typ: v.Type(), ptr: v.Pointer()}
+	if v.Kind() == reflect.Slice {
+		key.len = v.Len()
+	}
+
+	return key
+}
+
+// open opens a composite literal.
+func (p *printer) open() {
+	p.buf.WriteByte('{')
+	p.depth++
+}
+
+// close closes a composite literal.
+func (p *printer) close() {
+	p.depth--
+	p.newline()
+	p.buf.WriteByte('}')
+}
+
+// newline starts a new line at the current indentation.
+func (p *printer) newline() {
+	p.buf.WriteByte('\n')
+	p.buf.WriteString(strings.Repeat("\t", p.depth))
+}
+
+// typeName returns the name of typ as it would be written in Go source e.g.
+// []any rather than reflect's []interface {}.
+func typeName(typ reflect.Type) string {
+	if typ == reflect.TypeFor[[]byte]() {
+		return "[]byte"
+	}
+
+	return strings.ReplaceAll(typ.String(), "interface {}", "any")
+}
+
+// root returns path, or "." if path is the root of the value.
+func root(path string) string {
+	if path == "" {
+		return "."
+	}
+
+	if strings.HasPrefix(path, "[") {
+		return "." + path
+	}
+
+	return path
+}
+
+// keyPath returns the representation of a map key in a path.
+func keyPath(key reflect.Value) string {
+	for key.Kind() == reflect.Interface && !key.IsNil() {
+		key = key.Elem()
+	}
+
+	switch key.Kind() {
+	case reflect.String:
+		return strconv.Quote(key.String())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(key.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(key.Uint(), 10)
+	case reflect.Bool:
+		return strconv.FormatBool(key.Bool())
+	default:
+		return key.Type().String()
+	}
+}
+
+// compare orders map keys: numbers numerically, strings lexically, false before
+// true and anything else by its pretty printed representation.
+//
+// Keys of different types (in a map with interface keys) are ordered by type first.
+func compare(a, b reflect.Value) int {
+	for a.Kind() == reflect.Interface && !a.IsNil() {
+		a = a.Elem()
+	}
+
+	for b.Kind() == reflect.Interface && !b.IsNil() {
+		b = b.Elem()
+	}
+
+	if a.Type() != b.Type() {
+		return cmp.Compare(a.Type().String(), b.Type().String())
+	}
+
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cmp.Compare(a.Int(), b.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return cmp.Compare(a.Uint(), b.Uint())
+	case reflect.Float32, reflect.Float64:
+		return cmp.Compare(a.Float(), b.Float())
+	case reflect.String:
+		return cmp.Compare(a.String(), b.String())
+	case reflect.Bool:
+		switch {
+		case a.Bool() == b.Bool():
+			return 0
+		case b.Bool():
+			return -1
+		default:
+			return 1
+		}
+	default:
+		return bytes.Compare(pretty(a), pretty(b))
+	}
+}
@@ -5,6 +5,9 @@ import (
 	"bytes"
 	"encoding"
 	"fmt"
+	"reflect"
+
+	"go.followtheprocess.codes/snapshot/internal/format"
 )
 
 // Formatter implements [snapshot.Formatter] and returns a simple plain text
@@ -22,10 +25,15 @@ func (f Formatter) Ext() string {
 }
 
 // Format returns a plain text snapshot of the value.
+//
+// A [format.Snapshotter], whether the value itself or nested inside it, is
+// written as its snapshot.
 func (f Formatter) Format(value any) ([]byte, error) {
 	buf := &bytes.Buffer{}
 
-	switch val := value.(type) {
+	switch val := format.Snapshot(value).(type) {
+	case nil:
+		buf.WriteString("<nil>")
 	case encoding.TextMarshaler:
 		content, err := val.MarshalText()
 		if err != nil {
@@ -56,8 +64,8 @@ func (f Formatter) Format(value any) ([]byte, error) {
 		// For any primitive type just use %+v
 		fmt.Fprintf(buf, "%+v", val)
 	default:
-		// Fallback, use %#v as a best effort at generic printing
-		fmt.Fprintf(buf, "%#v", val)
+		// Anything else gets pretty printed, one field or element per line
+		buf.Write(pretty(reflect.ValueOf(val)))
 	}
 
 	return buf.Bytes(), nil
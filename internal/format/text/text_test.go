@@ -1,10 +1,13 @@
 package text_test
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
+	"go.followtheprocess.codes/snapshot/internal/format/formattest"
 	"go.followtheprocess.codes/snapshot/internal/format/text"
 	"go.followtheprocess.codes/test"
 )
@@ -30,6 +33,21 @@ type person struct {
 	age  int
 }
 
+type node struct {
+	next  *node
+	name  string
+	Tags  map[string]float64
+	Owner *person
+	Items []any
+}
+
+// event has unexported fields that describe themselves.
+type event struct {
+	created time.Time
+	err     error
+	name    stringer
+}
+
 func TestFormatter(t *testing.T) {
 	tests := []struct {
 		value any
@@ -59,6 +77,47 @@ func TestFormatter(t *testing.T) {
 			name:  "struct",
 			value: person{name: "Tom", age: 31},
 		},
+		{
+			name: "nested",
+			value: node{
+				name:  "root",
+				Owner: &person{name: "Tom", age: 31},
+				Tags:  map[string]float64{"zeta": 0.1, "alpha": 1e21, "mid": 2.5},
+				Items: []any{1, "two", nil, stringer{txt: "three"}, []byte("four"), make(chan int), func() {}, 3.0},
+			},
+		},
+		{
+			name: "cycle",
+			value: func() *node {
+				first := &node{name: "first"}
+				first.next = &node{name: "second", next: first}
+
+				return first
+			}(),
+		},
+		{
+			// Unexported fields are described by their methods like any other, rather
+			// than by their own (machine dependent) unexported fields
+			name: "unexported_methods",
+			value: event{
+				created: time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC),
+				err:     errors.New("file not found"),
+				name:    stringer{txt: "deploy"},
+			},
+		},
+		{
+			name:  "map_int_keys",
+			value: map[int]bool{10: true, 2: false, -1: true},
+		},
+		{
+			name:  "nil_values",
+			value: node{},
+		},
+		{
+			// Snapshotters are honoured wherever they are
+			name:  "snapshotter",
+			value: map[string]any{"session": formattest.Session{User: "jane", Token: "secret"}},
+		},
 	}
 
 	for _, tt := range tests {
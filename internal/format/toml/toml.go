@@ -0,0 +1,104 @@
+// Package toml provides a TOML formatter for snapshots.
+package toml
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+
+	"github.com/BurntSushi/toml"
+	"go.followtheprocess.codes/snapshot/internal/format"
+)
+
+// limits are the things TOML can't encode, beyond those no encoder can.
+var limits = format.Limits{StringKeys: true} //nolint:gochecknoglobals // Effectively a constant
+
+// Formatter implements [snapshot.Formatter] and returns a TOML
+// snapshot format.
+type Formatter struct{}
+
+// NewFormatter returns a new TOML Formatter.
+func NewFormatter() Formatter {
+	return Formatter{}
+}
+
+// Ext returns the file extension for a TOML snapshot.
+func (f Formatter) Ext() string {
+	return ".snap.toml"
+}
+
+// Lossy returns a description of each part of value that would be silently
+// encoded as an empty table, see [format.Lossy].
+func (f Formatter) Lossy(value any) []string {
+	return format.Lossy(value, "toml")
+}
+
+// Format returns a TOML formatted snapshot of the value.
+//
+// A TOML document is a table, so the value must be a struct or a map (or a pointer
+// to one). Map keys are sorted, struct fields are written in the order they are
+// declared, and nested tables and arrays of tables come after the plain keys of
+// the table they're in, as TOML requires.
+//
+// A [format.Snapshotter] is encoded as its snapshot, anything TOML can't encode,
+// chans, funcs etc. is replaced by a placeholder and errors are encoded as their
+// message, see [format.Normalise].
+func (f Formatter) Format(value any) ([]byte, error) {
+	value = format.Normalise(value, limits)
+
+	// The encoder will happily write a lone value, which isn't a TOML document
+	if kind := reflect.Indirect(reflect.ValueOf(value)).Kind(); kind != reflect.Struct && kind != reflect.Map {
+		return nil, fmt.Errorf("a TOML document must be a table (a struct or map), got %T", value)
+	}
+
+	buf := &bytes.Buffer{}
+
+	encoder := toml.NewEncoder(buf)
+	encoder.Indent = ""
+
+	if err := encoder.Encode(value); err != nil {
+		return nil, fmt.Errorf("failed to encode value: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Decode decodes a TOML snapshot back into structured data.
+//
+// Arrays of tables are decoded as []any like any other array, rather than the
+// []map[string]any the TOML decoder produces.
+func (f Formatter) Decode(data []byte) (any, error) {
+	var value map[string]any
+	if _, err := toml.Decode(string(data), &value); err != nil {
+		return nil, fmt.Errorf("failed to decode value: %w", err)
+	}
+
+	return generic(value), nil
+}
+
+// generic returns value with every []map[string]any replaced by an []any.
+func generic(value any) any {
+	switch value := value.(type) {
+	case map[string]any:
+		for key, elem := range value {
+			value[key] = generic(elem)
+		}
+
+		return value
+	case []any:
+		for i, elem := range value {
+			value[i] = generic(elem)
+		}
+
+		return value
+	case []map[string]any:
+		elems := make([]any, 0, len(value))
+		for _, elem := range value {
+			elems = append(elems, generic(elem))
+		}
+
+		return elems
+	default:
+		return value
+	}
+}
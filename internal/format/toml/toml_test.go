@@ -0,0 +1,116 @@
+package toml_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.followtheprocess.codes/snapshot/internal/format/toml"
+	"go.followtheprocess.codes/test"
+)
+
+type server struct {
+	Host string `toml:"host"`
+	Port int    `toml:"port"`
+}
+
+type config struct {
+	Started  time.Time         `toml:"started"`
+	Labels   map[string]string `toml:"labels"`
+	Name     string            `toml:"name"`
+	Servers  []server          `toml:"servers"`
+	Database server            `toml:"database"`
+	Tags     []string          `toml:"tags"`
+	Version  int               `toml:"version"`
+}
+
+func TestFormatter(t *testing.T) {
+	tests := []struct {
+		value any
+		name  string
+	}{
+		{
+			name: "config",
+			value: config{
+				Name:     "snapshot",
+				Version:  2,
+				Tags:     []string{"go", "testing"},
+				Started:  time.Date(2024, time.March, 1, 12, 30, 0, 0, time.UTC),
+				Database: server{Host: "db.local", Port: 5432},
+				Servers:  []server{{Host: "a.local", Port: 80}, {Host: "b.local", Port: 81}},
+				Labels:   map[string]string{"zone": "eu", "env": "prod"},
+			},
+		},
+		{
+			// Map keys are sorted, nested tables come after the plain keys
+			name: "map_keys_sorted",
+			value: map[string]any{
+				"zebra":  1,
+				"apple":  map[string]any{"b": 2, "a": 1},
+				"banana": "yellow",
+			},
+		},
+		{
+			name: "array_of_tables",
+			value: map[string]any{
+				"fruit": []map[string]any{
+					{"name": "apple", "varieties": []map[string]any{{"name": "red delicious"}}},
+					{"name": "banana"},
+				},
+			},
+		},
+		{
+			name: "map_int_keys",
+			value: map[string]any{
+				"ports": map[int]string{443: "https", 80: "http"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test.ColorEnabled(os.Getenv("CI") == "")
+
+			path := filepath.Join("testdata", "TestFormatter", tt.name+".snap.toml")
+
+			want, err := os.ReadFile(path)
+			test.Ok(t, err)
+
+			got, err := toml.NewFormatter().Format(tt.value)
+			test.Ok(t, err)
+
+			test.DiffBytes(t, got, want)
+		})
+	}
+}
+
+func TestFormatterError(t *testing.T) {
+	// A TOML document must be a table
+	_, err := toml.NewFormatter().Format([]int{1, 2, 3})
+	test.Err(t, err)
+}
+
+func TestDecode(t *testing.T) {
+	formatter := toml.NewFormatter()
+
+	content, err := formatter.Format(map[string]any{
+		"name":    "snapshot",
+		"servers": []server{{Host: "a.local", Port: 80}},
+	})
+	test.Ok(t, err)
+
+	got, err := formatter.Decode(content)
+	test.Ok(t, err)
+
+	want := map[string]any{
+		"name":    "snapshot",
+		"servers": []any{map[string]any{"host": "a.local", "port": int64(80)}},
+	}
+
+	test.EqualFunc(t, got, any(want), reflect.DeepEqual)
+
+	_, err = formatter.Decode([]byte("not = toml = at all"))
+	test.Err(t, err)
+}
@@ -0,0 +1,40 @@
+package format
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// Accessible returns v, or if v was reached through an unexported struct field, an
+// equivalent value that can be used like any other e.g. to call its methods,
+// reporting whether that was possible.
+//
+// It's only possible if v is addressable, see [Addressable].
+func Accessible(v reflect.Value) (reflect.Value, bool) {
+	if !v.IsValid() || v.CanInterface() {
+		return v, true
+	}
+
+	if !v.CanAddr() {
+		return v, false
+	}
+
+	// Only ever read from, the value is snapshotted not modified
+	return reflect.NewAt(v.Type(), unsafe.Pointer(v.UnsafeAddr())).Elem(), true //nolint:gosec // See above
+}
+
+// Addressable returns v if it's addressable, otherwise an addressable copy of it,
+// so that its unexported fields are too.
+//
+// A value that was itself reached through an unexported field can't be copied, so
+// is returned as is.
+func Addressable(v reflect.Value) reflect.Value {
+	if !v.IsValid() || v.CanAddr() || !v.CanInterface() {
+		return v
+	}
+
+	copied := reflect.New(v.Type()).Elem()
+	copied.Set(v)
+
+	return copied
+}
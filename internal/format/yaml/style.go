@@ -0,0 +1,141 @@
+package yaml
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"go.followtheprocess.codes/snapshot/internal/format"
+	"go.yaml.in/yaml/v4"
+)
+
+// defaultLineWidth is the line width used when none is configured, it's the
+// YAML encoder's own default.
+const defaultLineWidth = 80
+
+// Config controls how values are laid out when encoded as YAML.
+//
+// The zero value is the default layout.
+type Config struct {
+	// LineWidth is the preferred line width, long strings are folded onto
+	// multiple lines to fit within it. 0 means the default of 80, and a
+	// negative width means strings are never folded.
+	LineWidth int
+
+	// FlowSequences is the maximum number of elements a sequence of single line
+	// scalars may have to be written in flow style e.g. [1, 2, 3], rather than
+	// one element per line. 0 means sequences are always written in block style.
+	FlowSequences int
+
+	// SortKeys sorts the keys of every mapping lexically, including struct
+	// fields which are otherwise written in the order they are declared.
+	SortKeys bool
+
+	// ParseText parses multi-line text holding a YAML mapping or sequence and
+	// re-encodes it canonically, rather than encoding it as a string.
+	ParseText bool
+}
+
+// Dump writes each value to w as a YAML document laid out according to cfg.
+//
+// Multi-line strings are written as literal block scalars (|) so that they read
+// (and diff) line by line, rather than as a quoted string full of \n escapes. The
+// exception is strings with whitespace at the end of a line, which YAML can't
+// represent as a block scalar, these are still double quoted.
+//
+// A [format.Snapshotter] is encoded as its snapshot, anything YAML can't encode,
+// chans, funcs etc. is replaced by a placeholder and errors are encoded as their
+// message, see [format.Normalise].
+func Dump(w io.Writer, cfg Config, values ...any) error {
+	width := cfg.LineWidth
+
+	switch {
+	case width == 0:
+		width = defaultLineWidth
+	case width < 0:
+		width = -1
+	}
+
+	dumper, err := yaml.NewDumper(w, yaml.WithV3Defaults(), yaml.WithIndent(indent), yaml.WithLineWidth(width))
+	if err != nil {
+		return fmt.Errorf("could not create YAML encoder: %w", err)
+	}
+
+	for _, value := range values {
+		var node yaml.Node
+		if err := node.Encode(format.Normalise(value, format.Limits{})); err != nil {
+			return fmt.Errorf("failed to encode value: %w", err)
+		}
+
+		style(&node, cfg)
+
+		if err := dumper.Dump(&node); err != nil {
+			return fmt.Errorf("failed to encode value: %w", err)
+		}
+	}
+
+	return dumper.Close()
+}
+
+// style walks the tree rooted at node, applying the styling in cfg.
+func style(node *yaml.Node, cfg Config) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if multiline(node) {
+			node.Style = yaml.LiteralStyle
+		}
+	case yaml.MappingNode:
+		if cfg.SortKeys {
+			sortKeys(node)
+		}
+	case yaml.SequenceNode:
+		if cfg.FlowSequences > 0 && len(node.Content) > 0 && len(node.Content) <= cfg.FlowSequences && simple(node) {
+			node.Style = yaml.FlowStyle
+		}
+	}
+
+	for _, child := range node.Content {
+		style(child, cfg)
+	}
+}
+
+// multiline reports whether node is a string scalar spanning more than one line.
+func multiline(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.ShortTag() == "!!str" && strings.Contains(node.Value, "\n")
+}
+
+// simple reports whether every element of a sequence is a single line scalar, and
+// so can be written in flow style.
+func simple(node *yaml.Node) bool {
+	for _, child := range node.Content {
+		if child.Kind != yaml.ScalarNode || multiline(child) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// sortKeys sorts the key value pairs of a mapping node by key.
+func sortKeys(node *yaml.Node) {
+	type pair struct {
+		key   *yaml.Node
+		value *yaml.Node
+	}
+
+	pairs := make([]pair, 0, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		pairs = append(pairs, pair{key: node.Content[i], value: node.Content[i+1]})
+	}
+
+	slices.SortStableFunc(pairs, func(a, b pair) int {
+		return cmp.Compare(a.key.Value, b.key.Value)
+	})
+
+	for i, p := range pairs {
+		node.Content[2*i] = p.key
+		node.Content[2*i+1] = p.value
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"bytes"
 	"fmt"
 
+	"go.followtheprocess.codes/snapshot/internal/format"
 	"go.yaml.in/yaml/v4"
 )
 
@@ -18,11 +19,14 @@ const indent = 2
 
 // Formatter implements [snapshot.Formatter] and returns a YAML
 // snapshot format.
-type Formatter struct{}
+type Formatter struct {
+	config Config
+}
 
-// NewFormatter returns a new YAML Formatter.
-func NewFormatter() Formatter {
-	return Formatter{}
+// NewFormatter returns a new YAML Formatter, laying out snapshots
+// according to config.
+func NewFormatter(config Config) Formatter {
+	return Formatter{config: config}
 }
 
 // Ext returns the file extension for a YAML snapshot.
@@ -30,17 +34,78 @@ func (f Formatter) Ext() string {
 	return ".snap.yaml"
 }
 
+// Lossy returns a description of each part of value that would be silently
+// encoded as an empty {}, see [format.Lossy].
+func (f Formatter) Lossy(value any) []string {
+	return format.Lossy(value, "yaml")
+}
+
 // Format returns a YAML formatted snapshot of the value.
+//
+// If the config says to parse text and the value is a string or []byte holding a
+// multi-line YAML document whose root is a mapping or sequence, it's parsed and
+// re-encoded canonically with sorted keys and consistent indentation, rather than
+// encoded as a string. It's not the default as plenty of ordinary text, CLI help
+// for instance, happens to be valid YAML.
 func (f Formatter) Format(value any) ([]byte, error) {
-	buf := &bytes.Buffer{}
-	encoder := yaml.NewEncoder(buf)
-	encoder.SetIndent(indent)
+	// A Snapshotter may well present itself as YAML text
+	value = format.Snapshot(value)
 
-	if err := encoder.Encode(value); err != nil {
-		return nil, fmt.Errorf("failed to encode value: %w", err)
+	if f.config.ParseText {
+		if document, ok := encoded(value); ok {
+			value = document
+		}
 	}
 
-	encoder.Close()
+	buf := &bytes.Buffer{}
+
+	if err := Dump(buf, f.config, value); err != nil {
+		return nil, err
+	}
 
 	return buf.Bytes(), nil
 }
+
+// Decode decodes a YAML snapshot back into structured data.
+func (f Formatter) Decode(data []byte) (any, error) {
+	var value any
+	if err := yaml.Unmarshal(data, &value); err != nil {
+		return nil, fmt.Errorf("failed to decode value: %w", err)
+	}
+
+	return value, nil
+}
+
+// encoded returns the parsed YAML document held in value, reporting whether value
+// holds one at all.
+//
+// Almost any text is valid YAML, a single line is a plain scalar and "key: value"
+// is a mapping, so only multi-line text whose root is a mapping or sequence counts.
+func encoded(value any) (any, bool) {
+	var text []byte
+
+	switch value := value.(type) {
+	case []byte:
+		text = value
+	case string:
+		text = []byte(value)
+	default:
+		return nil, false
+	}
+
+	if !bytes.Contains(bytes.TrimSpace(text), []byte("\n")) {
+		return nil, false
+	}
+
+	var document any
+	if err := yaml.Unmarshal(text, &document); err != nil {
+		return nil, false
+	}
+
+	switch document.(type) {
+	case map[string]any, map[any]any, []any:
+		return document, true
+	default:
+		return nil, false
+	}
+}
@@ -1,10 +1,15 @@
 package yaml_test
 
 import (
+	"errors"
+	"math"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
+	"go.followtheprocess.codes/snapshot/internal/format/formattest"
 	"go.followtheprocess.codes/snapshot/internal/format/yaml"
 	"go.followtheprocess.codes/test"
 )
@@ -17,8 +22,9 @@ type config struct {
 
 func TestFormatter(t *testing.T) {
 	tests := []struct {
-		value any
-		name  string
+		value  any
+		name   string
+		config yaml.Config
 	}{
 		{
 			name:  "empty",
@@ -52,6 +58,85 @@ func TestFormatter(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "multiline",
+			value: map[string]any{
+				"output":   "Usage: demo [OPTIONS]\n\nOptions:\n  --help  Show help\n",
+				"trailing": "padded \nline",
+				"tabs":     "a\tb\nc\td",
+			},
+		},
+		{
+			name: "sort_keys",
+			value: struct {
+				Zebra string `yaml:"zebra"`
+				Apple string `yaml:"apple"`
+			}{Zebra: "z", Apple: "a"},
+			config: yaml.Config{SortKeys: true},
+		},
+		{
+			name: "flow_sequences",
+			value: map[string]any{
+				"short":     []int{1, 2, 3},
+				"long":      []int{1, 2, 3, 4, 5},
+				"multiline": []string{"one\ntwo"},
+				"nested":    [][]int{{1}, {2}},
+			},
+			config: yaml.Config{FlowSequences: 3},
+		},
+		{
+			// Things the encoder can't handle are replaced with placeholders rather
+			// than failing the test
+			name: "unsupported",
+			value: struct {
+				Callback func()      `yaml:"callback"`
+				Events   chan int    `yaml:"events"`
+				Err      error       `yaml:"err"`
+				Labels   map[any]any `yaml:"labels"`
+				Nothing  func()      `yaml:"nothing"`
+				Name     string      `yaml:"name"`
+				Ratio    float64     `yaml:"ratio"`
+			}{
+				Callback: func() {},
+				Events:   make(chan int),
+				Err:      errors.New("file not found"),
+				Labels:   map[any]any{1: "one", "two": 2},
+				Name:     "unsupported",
+				Ratio:    math.NaN(),
+			},
+		},
+		{
+			// Already encoded documents are re-encoded canonically, not as a string
+			name:   "yaml_text",
+			value:  "zebra: 1\napple:\n      b: [1, 2]\n      a: hello\n",
+			config: yaml.Config{ParseText: true},
+		},
+		{
+			name:   "yaml_bytes",
+			value:  []byte("- b: true\n  a: null\n"),
+			config: yaml.Config{ParseText: true},
+		},
+		{
+			// Valid YAML mapping, but a single line is more likely an ordinary string
+			name:   "single_line_text",
+			value:  "key: value",
+			config: yaml.Config{ParseText: true},
+		},
+		{
+			// Valid YAML too, but without ParseText it's text and must be kept as is
+			name:  "cli_text",
+			value: "Usage: demo [OPTIONS]\n\nOptions:\n  --help  Show help\n",
+		},
+		{
+			name:   "line_width",
+			value:  strings.TrimSpace(strings.Repeat("word ", 30)),
+			config: yaml.Config{LineWidth: -1},
+		},
+		{
+			// Snapshotters are honoured wherever they are
+			name:  "snapshotter",
+			value: map[string]any{"session": formattest.Session{User: "jane", Token: "secret"}},
+		},
 	}
 
 	for _, tt := range tests {
@@ -63,7 +148,7 @@ func TestFormatter(t *testing.T) {
 			want, err := os.ReadFile(path)
 			test.Ok(t, err)
 
-			got, err := yaml.NewFormatter().Format(tt.value)
+			got, err := yaml.NewFormatter(tt.config).Format(tt.value)
 			test.Ok(t, err)
 
 			test.DiffBytes(t, got, want)
@@ -71,8 +156,36 @@ func TestFormatter(t *testing.T) {
 	}
 }
 
+// broken is a value that fails to marshal itself.
+type broken struct{}
+
+func (broken) MarshalYAML() (any, error) {
+	return nil, errors.New("broken")
+}
+
 func TestFormatterError(t *testing.T) {
-	// Channels cannot be marshalled, the underlying error must be propagated
-	_, err := yaml.NewFormatter().Format(make(chan int))
+	// The underlying error must be propagated rather than swallowed
+	_, err := yaml.NewFormatter(yaml.Config{}).Format(broken{})
+	test.Err(t, err)
+}
+
+func TestDecode(t *testing.T) {
+	formatter := yaml.NewFormatter(yaml.Config{})
+
+	content, err := formatter.Format(config{Name: "snapshot", Version: 2, Tags: []string{"go"}})
+	test.Ok(t, err)
+
+	got, err := formatter.Decode(content)
+	test.Ok(t, err)
+
+	want := map[string]any{
+		"name":    "snapshot",
+		"version": 2,
+		"tags":    []any{"go"},
+	}
+
+	test.EqualFunc(t, got, any(want), reflect.DeepEqual)
+
+	_, err = formatter.Decode([]byte("key: [unclosed"))
 	test.Err(t, err)
 }
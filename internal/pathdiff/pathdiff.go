@@ -0,0 +1,251 @@
+// Package pathdiff computes a path oriented diff between two structured documents,
+// as decoded from JSON or YAML into any.
+//
+// Rather than lines, the differences are reported against the path to the
+// value that changed e.g.
+//
+//	.users[2].email: "a@x" → "b@x"
+//	.items: 3 elements added
+//
+// Which is much easier to read than a line diff of a large document.
+package pathdiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// maxSummary is the maximum number of characters of a string shown when
+// summarising a value, longer strings are truncated.
+const maxSummary = 60
+
+// identifier matches map keys that can be written as .key in a path, anything
+// else is written as ["key"].
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// Kind is the kind of a [Change].
+type Kind int
+
+const (
+	// Modified is a value present on both sides that differs.
+	Modified Kind = iota
+
+	// Added is a value only present in the new document.
+	Added
+
+	// Removed is a value only present in the old document.
+	Removed
+
+	// ElementsAdded is one or more elements appended to a sequence.
+	ElementsAdded
+
+	// ElementsRemoved is one or more elements removed from the end of a sequence.
+	ElementsRemoved
+)
+
+// Change is a single difference between two documents.
+type Change struct {
+	// Path is the path to the value that changed e.g. .users[2].email, the
+	// root of the document is ".".
+	Path string
+
+	// Old is the summarised old value, empty for [Added], [ElementsAdded]
+	// and [ElementsRemoved].
+	Old string
+
+	// New is the summarised new value, empty for [Removed], [ElementsAdded]
+	// and [ElementsRemoved].
+	New string
+
+	// Kind is the kind of change.
+	Kind Kind
+
+	// Count is the number of elements added or removed for [ElementsAdded]
+	// and [ElementsRemoved].
+	Count int
+}
+
+// String returns a human readable description of the change e.g.
+// .users[2].email: "a@x" → "b@x".
+func (c Change) String() string {
+	return c.Path + ": " + c.Summary()
+}
+
+// Summary returns a human readable description of the change without
+// the path e.g. "a@x" → "b@x".
+func (c Change) Summary() string {
+	switch c.Kind {
+	case Added:
+		return "added " + c.New
+	case Removed:
+		return "removed " + c.Old
+	case ElementsAdded:
+		return elements(c.Count) + " added"
+	case ElementsRemoved:
+		return elements(c.Count) + " removed"
+	default:
+		return c.Old + " → " + c.New
+	}
+}
+
+// Compare returns the changes from the before document to after, ordered by path.
+func Compare(before, after any) []Change {
+	return compare(nil, "", before, after)
+}
+
+// compare appends the changes from before to after, found at path, to changes.
+func compare(changes []Change, path string, before, after any) []Change {
+	oldMap, oldIsMap := mapping(before)
+	newMap, newIsMap := mapping(after)
+
+	if oldIsMap && newIsMap {
+		keys := make([]string, 0, len(oldMap)+len(newMap))
+		for key := range oldMap {
+			keys = append(keys, key)
+		}
+
+		for key := range newMap {
+			if _, ok := oldMap[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+
+		slices.Sort(keys)
+
+		for _, key := range keys {
+			oldValue, inOld := oldMap[key]
+			newValue, inNew := newMap[key]
+
+			child := join(path, segment(key))
+
+			switch {
+			case !inOld:
+				changes = append(changes, Change{Path: child, Kind: Added, New: summarise(newValue)})
+			case !inNew:
+				changes = append(changes, Change{Path: child, Kind: Removed, Old: summarise(oldValue)})
+			default:
+				changes = compare(changes, child, oldValue, newValue)
+			}
+		}
+
+		return changes
+	}
+
+	oldSeq, oldIsSeq := before.([]any)
+	newSeq, newIsSeq := after.([]any)
+
+	if oldIsSeq && newIsSeq {
+		for i := range min(len(oldSeq), len(newSeq)) {
+			changes = compare(changes, join(path, "["+strconv.Itoa(i)+"]"), oldSeq[i], newSeq[i])
+		}
+
+		switch {
+		case len(newSeq) > len(oldSeq):
+			changes = append(changes, Change{Path: root(path), Kind: ElementsAdded, Count: len(newSeq) - len(oldSeq)})
+		case len(oldSeq) > len(newSeq):
+			changes = append(changes, Change{Path: root(path), Kind: ElementsRemoved, Count: len(oldSeq) - len(newSeq)})
+		}
+
+		return changes
+	}
+
+	if !reflect.DeepEqual(before, after) {
+		changes = append(changes, Change{Path: root(path), Kind: Modified, Old: summarise(before), New: summarise(after)})
+	}
+
+	return changes
+}
+
+// mapping returns value as a map with string keys, reporting whether it was a map at all.
+//
+// YAML decodes mappings with non-string keys to map[any]any, the keys
+// of which are formatted with fmt.
+func mapping(value any) (map[string]any, bool) {
+	switch value := value.(type) {
+	case map[string]any:
+		return value, true
+	case map[any]any:
+		converted := make(map[string]any, len(value))
+		for key, val := range value {
+			converted[fmt.Sprint(key)] = val
+		}
+
+		return converted, true
+	default:
+		return nil, false
+	}
+}
+
+// segment returns the path segment for a map key.
+func segment(key string) string {
+	if identifier.MatchString(key) {
+		return "." + key
+	}
+
+	return "[" + strconv.Quote(key) + "]"
+}
+
+// join appends the path segment seg to path.
+func join(path, seg string) string {
+	if path == "" && strings.HasPrefix(seg, "[") {
+		// Indexing the root, which is written as .[0] not [0]
+		return "." + seg
+	}
+
+	return path + seg
+}
+
+// root returns path, or "." if path is the root of the document.
+func root(path string) string {
+	if path == "" {
+		return "."
+	}
+
+	return path
+}
+
+// summarise returns a short, single line summary of a value.
+func summarise(value any) string {
+	if m, ok := mapping(value); ok {
+		if len(m) == 0 {
+			return "{}"
+		}
+
+		return "{…}"
+	}
+
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		if runes := []rune(value); len(runes) > maxSummary {
+			return strconv.Quote(string(runes[:maxSummary])) + "…"
+		}
+
+		return strconv.Quote(value)
+	case json.Number:
+		return value.String()
+	case []any:
+		if len(value) == 0 {
+			return "[]"
+		}
+
+		return "[…]"
+	default:
+		return strings.TrimSpace(fmt.Sprint(value))
+	}
+}
+
+// elements returns "1 element" or "n elements".
+func elements(n int) string {
+	if n == 1 {
+		return "1 element"
+	}
+
+	return strconv.Itoa(n) + " elements"
+}
@@ -0,0 +1,107 @@
+package pathdiff_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"go.followtheprocess.codes/snapshot/internal/pathdiff"
+	"go.followtheprocess.codes/test"
+)
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		before any      // The old document
+		after  any      // The new document
+		name   string   // Name of the test case
+		want   []string // Expected changes, formatted with String
+	}{
+		{
+			name:   "equal",
+			before: map[string]any{"a": "b"},
+			after:  map[string]any{"a": "b"},
+			want:   nil,
+		},
+		{
+			name:   "root scalar",
+			before: "one",
+			after:  "two",
+			want:   []string{`.: "one" → "two"`},
+		},
+		{
+			name: "nested",
+			before: map[string]any{
+				"users": []any{
+					map[string]any{"email": "a@x"},
+					map[string]any{"email": "a@x"},
+				},
+			},
+			after: map[string]any{
+				"users": []any{
+					map[string]any{"email": "a@x"},
+					map[string]any{"email": "b@x"},
+				},
+			},
+			want: []string{`.users[1].email: "a@x" → "b@x"`},
+		},
+		{
+			name:   "keys added and removed",
+			before: map[string]any{"a": 1, "b": 2},
+			after:  map[string]any{"b": 2, "c": map[string]any{"d": true}},
+			want:   []string{`.a: removed 1`, `.c: added {…}`},
+		},
+		{
+			name:   "elements added",
+			before: map[string]any{"items": []any{1}},
+			after:  map[string]any{"items": []any{1, 2, 3, 4}},
+			want:   []string{`.items: 3 elements added`},
+		},
+		{
+			name:   "element removed",
+			before: []any{"a", "b"},
+			after:  []any{"a"},
+			want:   []string{`.: 1 element removed`},
+		},
+		{
+			name:   "root index",
+			before: []any{"a"},
+			after:  []any{"b"},
+			want:   []string{`.[0]: "a" → "b"`},
+		},
+		{
+			name:   "awkward keys",
+			before: map[any]any{"has space": 1, 2: "x"},
+			after:  map[any]any{"has space": 2, 2: "y"},
+			want:   []string{`.["2"]: "x" → "y"`, `.["has space"]: 1 → 2`},
+		},
+		{
+			name:   "type changed",
+			before: map[string]any{"a": []any{1}},
+			after:  map[string]any{"a": nil},
+			want:   []string{`.a: […] → null`},
+		},
+		{
+			name:   "json numbers",
+			before: map[string]any{"n": json.Number("12345678901234567890")},
+			after:  map[string]any{"n": json.Number("12345678901234567891")},
+			want:   []string{`.n: 12345678901234567890 → 12345678901234567891`},
+		},
+		{
+			name:   "long string",
+			before: strings.Repeat("a", 100),
+			after:  "",
+			want:   []string{`.: "` + strings.Repeat("a", 60) + `"… → ""`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, change := range pathdiff.Compare(tt.before, tt.after) {
+				got = append(got, change.String())
+			}
+
+			test.Diff(t, strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package render
+
+import (
+	"bytes"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// maxInlineCells is the largest LCS table we're prepared to build when diffing
+	// a line pair inline, the LCS is O(mn) so this avoids pathological cost on very
+	// long lines that are also very different. Common prefixes and suffixes are
+	// trimmed first so long lines with a small change (e.g. minified JSON) are fine.
+	maxInlineCells = 1 << 20
+
+	// similarityThreshold is the minimum similarity ratio (2*equal / total) a
+	// line pair must have to be highlighted inline, highlighting the changes
+	// between two completely different lines is just noise.
+	similarityThreshold = 0.5
+)
+
+// segment is a contiguous run of text from a line, tagged as changed or not
+// relative to the other side of the diff.
+type segment struct {
+	text    []byte
+	changed bool
+}
+
+// inlineDiff diffs a removed and added line pair token by token, returning
+// the segments making up each side.
+//
+// A trailing newline is never part of a changed segment so highlighting
+// doesn't bleed onto the next line in a terminal, and invisible characters are
+// replaced by visible markers. If the only difference between the two lines is
+// whitespace, that is made visible too.
+func inlineDiff(removed, added []byte, highlight Highlight) (before, after []segment) {
+	x, y := split(removed), split(added)
+	whitespace := whitespaceOnly(x, y)
+
+	switch {
+	case highlight == HighlightNone:
+		before, after = plain(x.text), plain(y.text)
+	case !utf8.Valid(x.text) || !utf8.Valid(y.text):
+		before, after = whole(x.text), whole(y.text)
+	default:
+		var ok bool
+
+		before, after, ok = lcs(tokenise(x.text, highlight), tokenise(y.text, highlight))
+		if !ok {
+			before, after = whole(x.text), whole(y.text)
+		}
+	}
+
+	return x.finish(before, whitespace, y), y.finish(after, whitespace, x)
+}
+
+// single returns the segments making up a removed or added line that has no
+// counterpart on the other side of the diff.
+//
+// Invisible characters are replaced by visible markers and if the line is
+// entirely whitespace, that is made visible too.
+func single(content []byte) []segment {
+	l := split(content)
+
+	return l.finish(plain(l.text), l.blank(), l)
+}
+
+// tokenise splits text into the tokens that are compared when diffing inline.
+//
+// With [HighlightWords], runs of letters and digits are kept together as are
+// runs of whitespace, and every other character is a token of its own. Otherwise
+// every character is a token.
+func tokenise(text []byte, highlight Highlight) [][]byte {
+	var tokens [][]byte
+
+	for len(text) > 0 {
+		r, size := utf8.DecodeRune(text)
+
+		if highlight == HighlightWords {
+			class := classify(r)
+			for class != classOther && size < len(text) {
+				next, n := utf8.DecodeRune(text[size:])
+				if classify(next) != class {
+					break
+				}
+
+				size += n
+			}
+		}
+
+		tokens = append(tokens, text[:size])
+		text = text[size:]
+	}
+
+	return tokens
+}
+
+// Character classes used to split text into words.
+const (
+	classOther = iota
+	classWord
+	classSpace
+)
+
+// classify returns the character class of r.
+func classify(r rune) int {
+	switch {
+	case r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r):
+		return classWord
+	case unicode.IsSpace(r):
+		return classSpace
+	default:
+		return classOther
+	}
+}
+
+// lcs computes the longest common subsequence of the tokens x and y, returning
+// the segments making up each side.
+//
+// It reports false if the two are too dissimilar to be worth highlighting, or
+// too large to diff cheaply.
+func lcs(x, y [][]byte) (before, after []segment, ok bool) {
+	// Trim the common prefix and suffix, it's cheap and on long lines with
+	// a small change it's most of the line
+	prefix := 0
+	for prefix < len(x) && prefix < len(y) && bytes.Equal(x[prefix], y[prefix]) {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(x)-prefix && suffix < len(y)-prefix &&
+		bytes.Equal(x[len(x)-1-suffix], y[len(y)-1-suffix]) {
+		suffix++
+	}
+
+	total := len(x) + len(y)
+	if total == 0 {
+		return nil, nil, true
+	}
+
+	for _, token := range x[:prefix] {
+		before = appendSegment(before, token, false)
+		after = appendSegment(after, token, false)
+	}
+
+	common := x[len(x)-suffix:]
+	x, y = x[prefix:len(x)-suffix], y[prefix:len(y)-suffix]
+	m, n := len(x), len(y)
+
+	if (m+1)*(n+1) > maxInlineCells {
+		return nil, nil, false
+	}
+
+	// table[i][j] is the length of the LCS of x[i:] and y[j:], flattened
+	// to avoid m+1 separate allocations
+	stride := n + 1
+	table := make([]int, (m+1)*stride)
+
+	for i := m - 1; i >= 0; i-- {
+		for j := n - 1; j >= 0; j-- {
+			if bytes.Equal(x[i], y[j]) {
+				table[i*stride+j] = table[(i+1)*stride+j+1] + 1
+			} else {
+				table[i*stride+j] = max(table[(i+1)*stride+j], table[i*stride+j+1])
+			}
+		}
+	}
+
+	equal := prefix + suffix
+
+	i, j := 0, 0
+	for i < m || j < n {
+		switch {
+		case i < m && j < n && bytes.Equal(x[i], y[j]):
+			before = appendSegment(before, x[i], false)
+			after = appendSegment(after, y[j], false)
+			equal++
+			i++
+			j++
+		case j < n && (i == m || table[i*stride+j+1] >= table[(i+1)*stride+j]):
+			after = appendSegment(after, y[j], true)
+			j++
+		default:
+			before = appendSegment(before, x[i], true)
+			i++
+		}
+	}
+
+	for _, token := range common {
+		before = appendSegment(before, token, false)
+		after = appendSegment(after, token, false)
+	}
+
+	if float64(2*equal)/float64(total) < similarityThreshold {
+		return nil, nil, false
+	}
+
+	return before, after, true
+}
+
+// appendSegment appends token to segs, merging it into the last segment if that
+// has the same changed state.
+func appendSegment(segs []segment, token []byte, changed bool) []segment {
+	if len(segs) > 0 && segs[len(segs)-1].changed == changed {
+		last := &segs[len(segs)-1]
+		last.text = append(last.text, token...)
+
+		return segs
+	}
+
+	return append(segs, segment{text: bytes.Clone(token), changed: changed})
+}
+
+// whole returns text as a single changed segment.
+func whole(text []byte) []segment {
+	if len(text) == 0 {
+		return nil
+	}
+
+	return []segment{{text: bytes.Clone(text), changed: true}}
+}
+
+// plain returns text as a single unchanged segment.
+func plain(text []byte) []segment {
+	if len(text) == 0 {
+		return nil
+	}
+
+	return []segment{{text: bytes.Clone(text)}}
+}
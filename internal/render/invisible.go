@@ -0,0 +1,128 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+)
+
+// noNewline is the marker the diff package appends to the last line of a text
+// that doesn't end in a newline.
+const noNewline = "\\ No newline at end of file\n"
+
+// Markers used to make whitespace visible.
+const (
+	markSpace   = "·"
+	markTab     = "→"
+	markCR      = "␍"
+	markNewline = "⏎"
+)
+
+// line is the content of a single line from a diff, taken apart so the line
+// terminator can be handled separately from the text.
+type line struct {
+	text    []byte // The text of the line, without any terminator
+	newline bool   // Whether the line really ended in a newline
+	marker  bool   // Whether the line is followed by the diff package's no newline marker
+}
+
+// split takes apart the content of a line from a diff.
+func split(content []byte) line {
+	if text, ok := bytes.CutSuffix(content, []byte("\n"+noNewline)); ok {
+		return line{text: text, marker: true}
+	}
+
+	text, newline := bytes.CutSuffix(content, []byte("\n"))
+
+	return line{text: text, newline: newline}
+}
+
+// finish puts a line back together from the segments of its text, revealing
+// invisible characters so they can be seen.
+//
+// If whitespace is true, spaces, tabs and the line's newline are revealed too,
+// with the newline highlighted if other doesn't have one.
+func (l line) finish(segs []segment, whitespace bool, other line) []segment {
+	revealed := make([]segment, 0, len(segs)+1)
+	for _, seg := range segs {
+		revealed = append(revealed, segment{text: reveal(seg.text, whitespace), changed: seg.changed})
+	}
+
+	if l.newline && whitespace {
+		revealed = appendSegment(revealed, []byte(markNewline), !other.newline)
+	}
+
+	if l.newline || l.marker {
+		revealed = appendSegment(revealed, []byte("\n"), false)
+	}
+
+	if l.marker {
+		revealed = appendSegment(revealed, []byte(noNewline), false)
+	}
+
+	return revealed
+}
+
+// whitespaceOnly reports whether the only differences between lines x and y are
+// in whitespace or otherwise invisible characters.
+func whitespaceOnly(x, y line) bool {
+	return visible(x.text) == visible(y.text)
+}
+
+// blank reports whether l consists only of whitespace or otherwise invisible characters.
+func (l line) blank() bool {
+	return visible(l.text) == ""
+}
+
+// visible returns text with all whitespace and invisible characters removed.
+func visible(text []byte) string {
+	return string(bytes.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || invisible(r) {
+			return -1
+		}
+
+		return r
+	}, text))
+}
+
+// invisible reports whether r is a character that doesn't show up in a terminal,
+// or shows up looking just like a normal space.
+func invisible(r rune) bool {
+	switch {
+	case r == '\t', r == '\n', r == ' ':
+		// Ordinary whitespace, only worth revealing when it's the difference
+		return false
+	case r == utf8.RuneError:
+		return false
+	default:
+		return unicode.IsSpace(r) || unicode.In(r, unicode.Cc, unicode.Cf)
+	}
+}
+
+// reveal returns text with invisible characters replaced with visible markers,
+// and if whitespace is true, with spaces and tabs replaced too.
+func reveal(text []byte, whitespace bool) []byte {
+	var buf []byte
+
+	for len(text) > 0 {
+		r, size := utf8.DecodeRune(text)
+
+		switch {
+		case whitespace && r == ' ':
+			buf = append(buf, markSpace...)
+		case whitespace && r == '\t':
+			buf = append(buf, markTab...)
+		case r == '\r':
+			buf = append(buf, markCR...)
+		case invisible(r):
+			buf = fmt.Appendf(buf, "<U+%04X>", r)
+		default:
+			buf = append(buf, text[:size]...)
+		}
+
+		text = text[size:]
+	}
+
+	return buf
+}
@@ -0,0 +1,55 @@
+package render
+
+import (
+	"fmt"
+
+	"go.followtheprocess.codes/snapshot/internal/pathdiff"
+)
+
+// maxPaths is the maximum number of path changes shown, beyond this a path
+// summary is no longer really a summary.
+const maxPaths = 50
+
+// Paths renders a path oriented summary of the changes between two structured
+// snapshots, one change per line, returning nil if there are no changes.
+func Paths(changes []pathdiff.Change, cfg Config) []byte {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	r := renderer{cfg: cfg}
+
+	var buf []byte
+
+	for i, change := range changes {
+		if i == maxPaths {
+			buf = fmt.Appendf(buf, "… and %d more\n", len(changes)-maxPaths)
+
+			break
+		}
+
+		buf = append(buf, change.Path...)
+		buf = append(buf, ": "...)
+
+		switch change.Kind {
+		case pathdiff.Added:
+			buf = append(buf, "added "...)
+			buf = r.style(buf, cfg.Theme.Added, []byte(change.New))
+		case pathdiff.Removed:
+			buf = append(buf, "removed "...)
+			buf = r.style(buf, cfg.Theme.Removed, []byte(change.Old))
+		case pathdiff.ElementsAdded:
+			buf = r.style(buf, cfg.Theme.Added, []byte(change.Summary()))
+		case pathdiff.ElementsRemoved:
+			buf = r.style(buf, cfg.Theme.Removed, []byte(change.Summary()))
+		default:
+			buf = r.style(buf, cfg.Theme.Removed, []byte(change.Old))
+			buf = append(buf, " → "...)
+			buf = r.style(buf, cfg.Theme.Added, []byte(change.New))
+		}
+
+		buf = append(buf, '\n')
+	}
+
+	return buf
+}
@@ -0,0 +1,484 @@
+// Package render renders the diff shown when a snapshot does not match.
+//
+// It is similar in spirit to go.followtheprocess.codes/diff/render but whether
+// or not colour is used is decided per call rather than by hue's process-wide
+// setting, so tests running in parallel with different colour configuration
+// cannot interfere with one another (or with anything else using hue).
+package render
+
+import (
+	"bytes"
+	"unicode"
+	"unicode/utf8"
+
+	"go.followtheprocess.codes/diff"
+	"go.followtheprocess.codes/hue"
+	"golang.org/x/text/width"
+)
+
+const (
+	escape = "\x1b["   // escape is the ANSI escape start sequence.
+	reset  = "\x1b[0m" // reset is the universal style reset sequence.
+)
+
+// separator is the column separator in the side by side layout.
+const separator = " │ "
+
+// Layout controls how a diff is laid out.
+type Layout int
+
+const (
+	// LayoutUnified is a unified diff with removed lines followed by added lines.
+	LayoutUnified Layout = iota
+
+	// LayoutSideBySide shows the old and new snapshots in two columns.
+	LayoutSideBySide
+
+	// LayoutPatch is a plain unified patch, suitable for git apply.
+	LayoutPatch
+)
+
+// Highlight controls the granularity of the highlighting within changed lines.
+type Highlight int
+
+const (
+	// HighlightCharacters highlights the individual characters that changed.
+	HighlightCharacters Highlight = iota
+
+	// HighlightWords highlights the words that changed.
+	HighlightWords
+
+	// HighlightNone highlights whole lines only.
+	HighlightNone
+)
+
+// Theme controls the styles and symbols used to render a diff.
+//
+// A zero style means the corresponding text is left unstyled.
+type Theme struct {
+	// Header is the style for the "diff" and "@@" header lines.
+	Header hue.Style
+
+	// RemovedHeader is the style for the "---" header line.
+	RemovedHeader hue.Style
+
+	// AddedHeader is the style for the "+++" header line.
+	AddedHeader hue.Style
+
+	// Removed is the style for lines only present in the old snapshot.
+	Removed hue.Style
+
+	// Added is the style for lines only present in the new snapshot.
+	Added hue.Style
+
+	// RemovedHighlight is the style for the changed characters within a removed line.
+	RemovedHighlight hue.Style
+
+	// AddedHighlight is the style for the changed characters within an added line.
+	AddedHighlight hue.Style
+
+	// RemovedSymbol is the symbol prefixed to removed lines e.g. "-".
+	RemovedSymbol string
+
+	// AddedSymbol is the symbol prefixed to added lines e.g. "+".
+	AddedSymbol string
+}
+
+// Config holds the configuration for rendering a diff.
+type Config struct {
+	// Theme is the theme to render the diff with, it is ignored by [LayoutPatch].
+	Theme Theme
+
+	// Layout is how the diff is laid out.
+	Layout Layout
+
+	// Highlight is the granularity of highlighting within changed lines.
+	Highlight Highlight
+
+	// Color is whether the diff may contain ANSI escape sequences, it is
+	// ignored by [LayoutPatch].
+	Color bool
+}
+
+// Render renders a diff according to cfg, returning nil if there are no differences.
+//
+// When a run of removed lines is immediately followed by an equal length run
+// of added lines, each pair is diffed inline and the changes highlighted.
+// Otherwise whole lines are styled.
+//
+// Invisible characters in changed lines (carriage returns, non-breaking and
+// zero width spaces etc.) are shown as visible markers. Where the only difference
+// between a pair of lines is whitespace, spaces, tabs and newlines are shown as
+// markers too.
+func Render(d diff.Diff, cfg Config) []byte {
+	lines := d.Lines()
+	if len(lines) == 0 {
+		return nil
+	}
+
+	r := renderer{cfg: cfg}
+
+	switch cfg.Layout {
+	case LayoutPatch:
+		return patch(lines)
+	case LayoutSideBySide:
+		return r.sideBySide(lines)
+	default:
+		return r.unified(lines)
+	}
+}
+
+// renderer renders the individual lines of a diff.
+type renderer struct {
+	cfg Config
+}
+
+// style appends text to dst, wrapped in the ANSI escape sequences for style
+// if colour is enabled.
+func (r renderer) style(dst []byte, style hue.Style, text []byte) []byte {
+	if !r.cfg.Color || style == 0 {
+		return append(dst, text...)
+	}
+
+	code, err := style.Code()
+	if err != nil {
+		// Invalid style, fall back to the raw text
+		return append(dst, text...)
+	}
+
+	dst = append(dst, escape...)
+	dst = append(dst, code...)
+	dst = append(dst, 'm')
+	dst = append(dst, text...)
+
+	return append(dst, reset...)
+}
+
+// unified renders lines as a unified diff.
+func (r renderer) unified(lines []diff.Line) []byte {
+	var buf []byte
+
+	i := 0
+	for i < len(lines) {
+		switch line := lines[i]; line.Kind {
+		case diff.KindHeader:
+			buf = r.header(buf, line.Content)
+			i++
+		case diff.KindContext:
+			buf = r.context(buf, line.Content)
+			i++
+		case diff.KindRemoved, diff.KindAdded:
+			buf, i = r.block(buf, lines, i)
+		default:
+			// Nothing sensible to do with a line kind we don't know about
+			i++
+		}
+	}
+
+	return buf
+}
+
+// header appends a diff header line.
+func (r renderer) header(dst, line []byte) []byte {
+	switch {
+	case bytes.HasPrefix(line, []byte("---")):
+		return r.style(dst, r.cfg.Theme.RemovedHeader, line)
+	case bytes.HasPrefix(line, []byte("+++")):
+		return r.style(dst, r.cfg.Theme.AddedHeader, line)
+	default:
+		return r.style(dst, r.cfg.Theme.Header, line)
+	}
+}
+
+// context appends an unchanged line, indented to line up with the changed ones.
+func (r renderer) context(dst, line []byte) []byte {
+	dst = r.pad(dst, r.gutter())
+
+	return append(dst, line...)
+}
+
+// pad appends n spaces to dst.
+func (r renderer) pad(dst []byte, n int) []byte {
+	for range n {
+		dst = append(dst, ' ')
+	}
+
+	return dst
+}
+
+// gutter returns the width of the prefix before the content of every line.
+func (r renderer) gutter() int {
+	symbol := max(
+		utf8.RuneCountInString(r.cfg.Theme.RemovedSymbol),
+		utf8.RuneCountInString(r.cfg.Theme.AddedSymbol),
+	)
+
+	return symbol + 1
+}
+
+// prefix returns the prefix for a changed line with the given symbol, padded
+// so that it is as wide as the gutter.
+func (r renderer) prefix(symbol string) []byte {
+	return r.pad([]byte(symbol), r.gutter()-utf8.RuneCountInString(symbol))
+}
+
+// block appends the run of changed lines starting at lines[i], returning the
+// extended buffer and the index of the first line after the run.
+func (r renderer) block(dst []byte, lines []diff.Line, i int) ([]byte, int) {
+	removed, added, i := changes(lines, i)
+
+	if len(removed) == len(added) {
+		for k := range removed {
+			before, after := inlineDiff(removed[k].Content, added[k].Content, r.cfg.Highlight)
+			dst = r.changed(dst, diff.KindRemoved, before)
+			dst = r.changed(dst, diff.KindAdded, after)
+		}
+
+		return dst, i
+	}
+
+	for _, line := range removed {
+		dst = r.changed(dst, diff.KindRemoved, single(line.Content))
+	}
+
+	for _, line := range added {
+		dst = r.changed(dst, diff.KindAdded, single(line.Content))
+	}
+
+	return dst, i
+}
+
+// changed appends a single removed or added line, made up of segs, highlighting
+// the segments that changed.
+func (r renderer) changed(dst []byte, kind diff.LineKind, segs []segment) []byte {
+	symbol, line, highlight := r.cfg.Theme.AddedSymbol, r.cfg.Theme.Added, r.cfg.Theme.AddedHighlight
+	if kind == diff.KindRemoved {
+		symbol, line, highlight = r.cfg.Theme.RemovedSymbol, r.cfg.Theme.Removed, r.cfg.Theme.RemovedHighlight
+	}
+
+	dst = r.style(dst, line, r.prefix(symbol))
+
+	for _, seg := range segs {
+		if seg.changed {
+			dst = r.style(dst, highlight, seg.text)
+		} else {
+			dst = r.style(dst, line, seg.text)
+		}
+	}
+
+	return dst
+}
+
+// changes returns the run of removed lines starting at lines[i] and the run of
+// added lines immediately following them, along with the index of the first line
+// after both.
+func changes(lines []diff.Line, i int) (removed, added []diff.Line, next int) {
+	start := i
+	for i < len(lines) && lines[i].Kind == diff.KindRemoved {
+		i++
+	}
+
+	end := i
+	for i < len(lines) && lines[i].Kind == diff.KindAdded {
+		i++
+	}
+
+	return lines[start:end], lines[end:i], i
+}
+
+// patch renders lines as a plain unified patch, with the single character
+// line prefixes that patch and git apply expect.
+func patch(lines []diff.Line) []byte {
+	var buf []byte
+
+	for _, line := range lines {
+		switch line.Kind {
+		case diff.KindHeader:
+			// git apply wants "diff --git a/file b/file", the diff package
+			// gives us "diff a/file b/file"
+			if rest, ok := bytes.CutPrefix(line.Content, []byte("diff ")); ok {
+				buf = append(buf, "diff --git "...)
+				buf = append(buf, rest...)
+			} else {
+				buf = append(buf, line.Content...)
+			}
+		case diff.KindContext:
+			buf = append(buf, ' ')
+			buf = append(buf, line.Content...)
+		case diff.KindRemoved:
+			buf = append(buf, '-')
+			buf = append(buf, line.Content...)
+		case diff.KindAdded:
+			buf = append(buf, '+')
+			buf = append(buf, line.Content...)
+		default:
+			// Nothing sensible to do with a line kind we don't know about
+		}
+	}
+
+	return buf
+}
+
+// cell is a single cell in the side by side layout, a missing line is
+// represented by the zero cell.
+type cell struct {
+	segs []segment     // The content of the cell, without a trailing newline
+	kind diff.LineKind // The kind of line in the cell
+	ok   bool          // Whether the cell holds a line at all
+}
+
+// width returns the display width of the cell's content, in terminal columns.
+func (c cell) width() int {
+	total := 0
+	for _, seg := range c.segs {
+		total += columns(seg.text)
+	}
+
+	return total
+}
+
+// columns returns the number of terminal columns text takes up: two for wide
+// characters like CJK and most emoji, none for combining marks and other
+// invisible characters like a zero width joiner, and one for anything else.
+func columns(text []byte) int {
+	total := 0
+
+	for len(text) > 0 {
+		r, size := utf8.DecodeRune(text)
+		text = text[size:]
+
+		switch {
+		case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+			// Nothing to see
+		case width.LookupRune(r).Kind() == width.EastAsianWide, width.LookupRune(r).Kind() == width.EastAsianFullwidth:
+			total += 2
+		default:
+			total++
+		}
+	}
+
+	return total
+}
+
+// row is a single row in the side by side layout.
+type row struct {
+	header []byte // If non-nil, the row is a header spanning both columns
+	left   cell   // The old snapshot
+	right  cell   // The new snapshot
+}
+
+// sideBySide renders lines with the old snapshot in the left column and the
+// new snapshot in the right.
+func (r renderer) sideBySide(lines []diff.Line) []byte {
+	var rows []row
+
+	i := 0
+	for i < len(lines) {
+		switch line := lines[i]; line.Kind {
+		case diff.KindHeader:
+			rows = append(rows, row{header: line.Content})
+			i++
+		case diff.KindContext:
+			c := newCell(diff.KindContext, []segment{{text: line.Content}})
+			rows = append(rows, row{left: c, right: c})
+			i++
+		case diff.KindRemoved, diff.KindAdded:
+			var removed, added []diff.Line
+
+			removed, added, i = changes(lines, i)
+			rows = append(rows, r.rows(removed, added)...)
+		default:
+			// Nothing sensible to do with a line kind we don't know about
+			i++
+		}
+	}
+
+	// The left column is as wide as its widest cell so the separators line up
+	width := 0
+	for _, row := range rows {
+		if row.header == nil {
+			width = max(width, row.left.width())
+		}
+	}
+
+	var buf []byte
+
+	for _, row := range rows {
+		if row.header != nil {
+			buf = r.header(buf, row.header)
+
+			continue
+		}
+
+		buf = r.cell(buf, row.left)
+		buf = r.pad(buf, width-row.left.width())
+		buf = append(buf, separator...)
+		buf = r.cell(buf, row.right)
+		buf = append(buf, '\n')
+	}
+
+	return buf
+}
+
+// rows lays out a run of removed lines and the added lines that follow them
+// as side by side rows, lining up the removed lines against the added ones.
+func (r renderer) rows(removed, added []diff.Line) []row {
+	rows := make([]row, max(len(removed), len(added)))
+
+	if len(removed) == len(added) {
+		for k := range removed {
+			before, after := inlineDiff(removed[k].Content, added[k].Content, r.cfg.Highlight)
+			rows[k].left = newCell(diff.KindRemoved, before)
+			rows[k].right = newCell(diff.KindAdded, after)
+		}
+
+		return rows
+	}
+
+	for k, line := range removed {
+		rows[k].left = newCell(diff.KindRemoved, single(line.Content))
+	}
+
+	for k, line := range added {
+		rows[k].right = newCell(diff.KindAdded, single(line.Content))
+	}
+
+	return rows
+}
+
+// cell appends a side by side cell to dst, prefixed with the gutter.
+func (r renderer) cell(dst []byte, c cell) []byte {
+	switch {
+	case !c.ok:
+		return r.pad(dst, r.gutter())
+	case c.kind == diff.KindContext:
+		dst = r.pad(dst, r.gutter())
+
+		for _, seg := range c.segs {
+			dst = append(dst, seg.text...)
+		}
+
+		return dst
+	default:
+		return r.changed(dst, c.kind, c.segs)
+	}
+}
+
+// newCell returns a cell of the given kind holding segs, which are cut at
+// the first newline.
+func newCell(kind diff.LineKind, segs []segment) cell {
+	c := cell{kind: kind, ok: true}
+
+	for _, seg := range segs {
+		text, _, found := bytes.Cut(seg.text, []byte("\n"))
+		if len(text) != 0 {
+			c.segs = append(c.segs, segment{text: text, changed: seg.changed})
+		}
+
+		if found {
+			break
+		}
+	}
+
+	return c
+}
@@ -0,0 +1,240 @@
+package render_test
+
+import (
+	"strings"
+	"testing"
+
+	"go.followtheprocess.codes/diff"
+	"go.followtheprocess.codes/hue"
+	"go.followtheprocess.codes/snapshot/internal/pathdiff"
+	"go.followtheprocess.codes/snapshot/internal/render"
+	"go.followtheprocess.codes/test"
+)
+
+var theme = render.Theme{
+	Header:           hue.Bold,
+	RemovedHeader:    hue.Red,
+	AddedHeader:      hue.Green,
+	Removed:          hue.Red,
+	Added:            hue.Green,
+	RemovedHighlight: hue.Black | hue.Bold | hue.RedBackground,
+	AddedHighlight:   hue.Black | hue.Bold | hue.GreenBackground,
+	RemovedSymbol:    "-",
+	AddedSymbol:      "+",
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name string // Name of the test case
+		old  string // Old snapshot
+		new  string // New snapshot
+		want string // Expected rendered diff
+		cfg  render.Config
+	}{
+		{
+			name: "equal",
+			old:  "same\n",
+			new:  "same\n",
+			cfg:  render.Config{Theme: theme},
+			want: "",
+		},
+		{
+			name: "changed line",
+			old:  "one\ntwo\nthree\n",
+			new:  "one\ntoo\nthree\n",
+			cfg:  render.Config{Theme: theme},
+			want: "diff old new\n--- old\n+++ new\n@@ -1,3 +1,3 @@\n  one\n- two\n+ too\n  three\n",
+		},
+		{
+			name: "added lines",
+			old:  "one\n",
+			new:  "one\ntwo\nthree\n",
+			cfg:  render.Config{Theme: theme},
+			want: "diff old new\n--- old\n+++ new\n@@ -1,1 +1,3 @@\n  one\n+ two\n+ three\n",
+		},
+		{
+			name: "custom symbols",
+			old:  "one\ntwo\n",
+			new:  "one\n2\n",
+			cfg: render.Config{
+				Theme: render.Theme{RemovedSymbol: "<<", AddedSymbol: ">"},
+			},
+			want: "diff old new\n--- old\n+++ new\n@@ -1,2 +1,2 @@\n   one\n<< two\n>  2\n",
+		},
+		{
+			name: "color",
+			old:  "one\ntwo\n",
+			new:  "one\ntoo\n",
+			cfg:  render.Config{Theme: theme, Color: true},
+			want: "\x1b[1mdiff old new\n\x1b[0m" +
+				"\x1b[31m--- old\n\x1b[0m" +
+				"\x1b[32m+++ new\n\x1b[0m" +
+				"\x1b[1m@@ -1,2 +1,2 @@\n\x1b[0m" +
+				"  one\n" +
+				"\x1b[31m- \x1b[0m\x1b[31mt\x1b[0m\x1b[1;30;41mw\x1b[0m\x1b[31mo\n\x1b[0m" +
+				"\x1b[32m+ \x1b[0m\x1b[32mt\x1b[0m\x1b[1;30;42mo\x1b[0m\x1b[32mo\n\x1b[0m",
+		},
+		{
+			name: "words",
+			old:  "the quick brown fox\n",
+			new:  "the quack brown fox\n",
+			cfg:  render.Config{Theme: theme, Color: true, Highlight: render.HighlightWords},
+			want: "\x1b[1mdiff old new\n\x1b[0m" +
+				"\x1b[31m--- old\n\x1b[0m" +
+				"\x1b[32m+++ new\n\x1b[0m" +
+				"\x1b[1m@@ -1,1 +1,1 @@\n\x1b[0m" +
+				"\x1b[31m- \x1b[0m\x1b[31mthe \x1b[0m\x1b[1;30;41mquick\x1b[0m\x1b[31m brown fox\n\x1b[0m" +
+				"\x1b[32m+ \x1b[0m\x1b[32mthe \x1b[0m\x1b[1;30;42mquack\x1b[0m\x1b[32m brown fox\n\x1b[0m",
+		},
+		{
+			name: "long line",
+			old:  strings.Repeat(`{"key":"value","n":1},`, 100) + "\n",
+			new:  `{"key":"value","n":2},` + strings.Repeat(`{"key":"value","n":1},`, 99) + "\n",
+			cfg:  render.Config{Theme: theme, Color: true},
+			want: "\x1b[1mdiff old new\n\x1b[0m" +
+				"\x1b[31m--- old\n\x1b[0m" +
+				"\x1b[32m+++ new\n\x1b[0m" +
+				"\x1b[1m@@ -1,1 +1,1 @@\n\x1b[0m" +
+				"\x1b[31m- \x1b[0m\x1b[31m" + `{"key":"value","n":` + "\x1b[0m\x1b[1;30;41m1\x1b[0m\x1b[31m}," +
+				strings.Repeat(`{"key":"value","n":1},`, 99) + "\n\x1b[0m" +
+				"\x1b[32m+ \x1b[0m\x1b[32m" + `{"key":"value","n":` + "\x1b[0m\x1b[1;30;42m2\x1b[0m\x1b[32m}," +
+				strings.Repeat(`{"key":"value","n":1},`, 99) + "\n\x1b[0m",
+		},
+		{
+			name: "no highlight",
+			old:  "one\ntwo\n",
+			new:  "one\ntoo\n",
+			cfg:  render.Config{Theme: theme, Color: true, Highlight: render.HighlightNone},
+			want: "\x1b[1mdiff old new\n\x1b[0m" +
+				"\x1b[31m--- old\n\x1b[0m" +
+				"\x1b[32m+++ new\n\x1b[0m" +
+				"\x1b[1m@@ -1,2 +1,2 @@\n\x1b[0m" +
+				"  one\n" +
+				"\x1b[31m- \x1b[0m\x1b[31mtwo\n\x1b[0m" +
+				"\x1b[32m+ \x1b[0m\x1b[32mtoo\n\x1b[0m",
+		},
+		{
+			name: "side by side",
+			old:  "one\ntwo\nthree\nfour\n",
+			new:  "one\ntoo\nthree\nfive\nsix\n",
+			cfg:  render.Config{Theme: theme, Layout: render.LayoutSideBySide},
+			want: "diff old new\n--- old\n+++ new\n@@ -1,4 +1,5 @@\n" +
+				"  one   │   one\n" +
+				"- two   │ + too\n" +
+				"  three │   three\n" +
+				"- four  │ + five\n" +
+				"        │ + six\n",
+		},
+		{
+			// Columns line up by display width, not the number of runes
+			name: "side by side wide",
+			old:  "名前\nok ✅\ne\u0301\n",
+			new:  "name\nok ❌\ne\u0301\n",
+			cfg:  render.Config{Theme: theme, Layout: render.LayoutSideBySide},
+			want: "diff old new\n--- old\n+++ new\n@@ -1,3 +1,3 @@\n" +
+				"- 名前  │ + name\n" +
+				"- ok ✅ │ + ok ❌\n" +
+				"  e\u0301     │   e\u0301\n",
+		},
+		{
+			name: "patch",
+			old:  "one\ntwo\nthree\n",
+			new:  "one\ntoo\nthree",
+			cfg:  render.Config{Theme: theme, Color: true, Layout: render.LayoutPatch},
+			want: "diff --git old new\n--- old\n+++ new\n@@ -1,3 +1,3 @@\n" +
+				" one\n-two\n-three\n+too\n+three\n\\ No newline at end of file\n",
+		},
+		{
+			name: "trailing whitespace",
+			old:  "a b\n",
+			new:  "a b \n",
+			cfg:  render.Config{Theme: theme},
+			want: "diff old new\n--- old\n+++ new\n@@ -1,1 +1,1 @@\n- a·b⏎\n+ a·b·⏎\n",
+		},
+		{
+			name: "tabs and spaces",
+			old:  "\tx\n",
+			new:  "    x\n",
+			cfg:  render.Config{Theme: theme},
+			want: "diff old new\n--- old\n+++ new\n@@ -1,1 +1,1 @@\n- →x⏎\n+ ····x⏎\n",
+		},
+		{
+			name: "missing final newline",
+			old:  "a\n",
+			new:  "a",
+			cfg:  render.Config{Theme: theme},
+			want: "diff old new\n--- old\n+++ new\n@@ -1,1 +1,1 @@\n- a⏎\n+ a\n\\ No newline at end of file\n",
+		},
+		{
+			name: "carriage return",
+			old:  "a\r\nb\n",
+			new:  "a\nb\n",
+			cfg:  render.Config{Theme: theme},
+			want: "diff old new\n--- old\n+++ new\n@@ -1,2 +1,2 @@\n- a␍⏎\n+ a⏎\n  b\n",
+		},
+		{
+			name: "non breaking space",
+			old:  "a\u00a0b\n",
+			new:  "a b\n",
+			cfg:  render.Config{Theme: theme},
+			want: "diff old new\n--- old\n+++ new\n@@ -1,1 +1,1 @@\n- a<U+00A0>b⏎\n+ a·b⏎\n",
+		},
+		{
+			name: "zero width space",
+			old:  "hello world\n",
+			new:  "hello\u200bthere\n",
+			cfg:  render.Config{Theme: theme},
+			want: "diff old new\n--- old\n+++ new\n@@ -1,1 +1,1 @@\n- hello world\n+ hello<U+200B>there\n",
+		},
+		{
+			name: "blank line",
+			old:  "a\n",
+			new:  "a\n\n",
+			cfg:  render.Config{Theme: theme},
+			want: "diff old new\n--- old\n+++ new\n@@ -1,1 +1,2 @@\n  a\n+ ⏎\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := diff.New("old", []byte(tt.old), "new", []byte(tt.new))
+
+			got := render.Render(d, tt.cfg)
+
+			test.Diff(t, string(got), tt.want)
+		})
+	}
+}
+
+func TestRenderNoColor(t *testing.T) {
+	// Even with a theme full of styles, no escape sequences should be present
+	// if colour is disabled, regardless of hue's global state
+	hue.Enabled(true)
+	t.Cleanup(func() { hue.Enabled(false) })
+
+	d := diff.New("old", []byte("one\ntwo\n"), "new", []byte("one\ntoo\n"))
+
+	got := render.Render(d, render.Config{Theme: theme, Color: false})
+
+	test.False(t, strings.Contains(string(got), "\x1b["), test.Context("got escape sequences with colour disabled"))
+}
+
+func TestPaths(t *testing.T) {
+	changes := []pathdiff.Change{
+		{Path: ".users[2].email", Kind: pathdiff.Modified, Old: `"a@x"`, New: `"b@x"`},
+		{Path: ".items", Kind: pathdiff.ElementsAdded, Count: 3},
+		{Path: ".name", Kind: pathdiff.Removed, Old: `"snapshot"`},
+	}
+
+	got := render.Paths(changes, render.Config{Theme: theme})
+	want := ".users[2].email: \"a@x\" → \"b@x\"\n.items: 3 elements added\n.name: removed \"snapshot\"\n"
+
+	test.Diff(t, string(got), want)
+
+	got = render.Paths(changes[:1], render.Config{Theme: theme, Color: true})
+	want = ".users[2].email: \x1b[31m\"a@x\"\x1b[0m → \x1b[32m\"b@x\"\x1b[0m\n"
+
+	test.Diff(t, string(got), want)
+
+	test.Equal(t, len(render.Paths(nil, render.Config{Theme: theme})), 0)
+}
@@ -4,8 +4,6 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
-
-	"go.followtheprocess.codes/hue"
 )
 
 // Option is a functional option for configuring a snapshot test [Runner].
@@ -50,6 +48,18 @@ func Description(description string) Option {
 	}
 }
 
+// Info is an [Option] that attaches arbitrary structured context to the snapshot, such as
+// the inputs to a table driven test, which is serialised as the insta "info" metadata field.
+//
+// Like [Description], it is only used by the default [InstaFormatter].
+func Info(info any) Option {
+	return func(r *Runner) error {
+		r.info = info
+
+		return nil
+	}
+}
+
 // Color is an [Option] that tells snapshot whether it can use ANSI terminal colors
 // when rendering the diff.
 //
@@ -57,9 +67,48 @@ func Description(description string) Option {
 // $NO_COLOR, $FORCE_COLOR, whether [os.Stdout] is a terminal etc.
 //
 // Passing this option will override default detection and set the provided value.
+//
+// The setting only applies to the [Runner] it is passed to, it does not affect
+// other runners or anything else in the process that might be using colour.
 func Color(enabled bool) Option {
 	return func(r *Runner) error {
-		hue.Enabled(enabled)
+		r.color = enabled
+
+		return nil
+	}
+}
+
+// WithComparer sets the [Comparer] used to decide whether a new snapshot
+// matches the one previously saved.
+//
+// The default is [ExactComparer], which requires snapshots to be identical, unless
+// the [Formatter] also implements [Comparer]. The default [InstaFormatter] does,
+// and compares only snapshot values, so changes to the metadata such as the
+// source file or description are reported but do not fail the test.
+func WithComparer(comparer Comparer) Option {
+	return func(r *Runner) error {
+		if comparer == nil {
+			return errors.New("cannot use a nil Comparer")
+		}
+
+		r.comparer = comparer
+
+		return nil
+	}
+}
+
+// WithTheme is an [Option] that sets the [Theme] used to render the diff when a
+// snapshot does not match.
+//
+// The default is [DefaultTheme], [ColorblindTheme] is also provided for those who
+// find red and green hard to tell apart, or you can build your own.
+func WithTheme(theme Theme) Option {
+	return func(r *Runner) error {
+		if theme.RemovedSymbol == "" || theme.AddedSymbol == "" {
+			return errors.New("theme must have both a removed and added symbol")
+		}
+
+		r.theme = theme
 
 		return nil
 	}
@@ -125,3 +174,51 @@ func WithFormatter(formatter Formatter) Option {
 		return nil
 	}
 }
+
+// ContextLines is an [Option] that sets the number of unchanged lines shown around
+// each change in the diff when a snapshot does not match.
+//
+// The default is 3, pass 0 to show only the lines that changed.
+func ContextLines(n int) Option {
+	return func(r *Runner) error {
+		if n < 0 {
+			return fmt.Errorf("context lines must not be negative, got %d", n)
+		}
+
+		r.context = n
+
+		return nil
+	}
+}
+
+// WithLayout is an [Option] that sets the [Layout] of the diff shown when a
+// snapshot does not match.
+//
+// The default is [LayoutUnified].
+func WithLayout(layout Layout) Option {
+	return func(r *Runner) error {
+		if layout < LayoutUnified || layout > LayoutPatch {
+			return fmt.Errorf("invalid layout: Layout(%d)", layout)
+		}
+
+		r.layout = layout
+
+		return nil
+	}
+}
+
+// WithHighlight is an [Option] that sets the granularity of the highlighting within
+// changed lines in the diff shown when a snapshot does not match.
+//
+// The default is [HighlightCharacters].
+func WithHighlight(highlight Highlight) Option {
+	return func(r *Runner) error {
+		if highlight < HighlightCharacters || highlight > HighlightNone {
+			return fmt.Errorf("invalid highlight: Highlight(%d)", highlight)
+		}
+
+		r.highlight = highlight
+
+		return nil
+	}
+}
@@ -0,0 +1,230 @@
+package snapshot
+
+import (
+	"os"
+	"path/filepath"
+
+	"go.followtheprocess.codes/diff"
+	"go.followtheprocess.codes/hue"
+	"go.followtheprocess.codes/snapshot/internal/pathdiff"
+	"go.followtheprocess.codes/snapshot/internal/render"
+	"golang.org/x/term"
+)
+
+// defaultContextLines is the default number of unchanged lines shown around each
+// change in a diff.
+const defaultContextLines = 3
+
+// Layout controls how the diff is laid out when a snapshot does not match.
+type Layout int
+
+const (
+	// LayoutUnified is the default layout, a unified diff with removed lines
+	// followed by added lines.
+	LayoutUnified Layout = iota
+
+	// LayoutSideBySide shows the old and new snapshots in two columns.
+	LayoutSideBySide
+
+	// LayoutPatch is a plain, uncoloured unified patch of the snapshot file that
+	// can be piped to git apply to accept the new snapshot. The file is named
+	// relative to the root of the git repository it's in, as git apply expects.
+	LayoutPatch
+)
+
+// Highlight controls the granularity of the highlighting within changed lines
+// when a snapshot does not match.
+type Highlight int
+
+const (
+	// HighlightCharacters is the default, the individual characters that
+	// changed are highlighted.
+	HighlightCharacters Highlight = iota
+
+	// HighlightWords highlights whole words that changed, which is often easier
+	// to read for long lines of prose or minified JSON.
+	HighlightWords
+
+	// HighlightNone turns off highlighting within lines, only whole lines
+	// are styled.
+	HighlightNone
+)
+
+// Theme controls how the diff is presented when a snapshot does not match.
+//
+// A zero [hue.Style] leaves the corresponding text unstyled, and styles are only
+// ever applied if colour is enabled, see [Color].
+type Theme struct {
+	// Header is the style for the "diff" and "@@" header lines.
+	Header hue.Style
+
+	// RemovedHeader is the style for the "---" header line.
+	RemovedHeader hue.Style
+
+	// AddedHeader is the style for the "+++" header line.
+	AddedHeader hue.Style
+
+	// Removed is the style for lines only present in the old snapshot.
+	Removed hue.Style
+
+	// Added is the style for lines only present in the new snapshot.
+	Added hue.Style
+
+	// RemovedHighlight is the style for the changed characters within a removed line.
+	RemovedHighlight hue.Style
+
+	// AddedHighlight is the style for the changed characters within an added line.
+	AddedHighlight hue.Style
+
+	// RemovedSymbol is the symbol prefixed to removed lines e.g. "-".
+	RemovedSymbol string
+
+	// AddedSymbol is the symbol prefixed to added lines e.g. "+".
+	AddedSymbol string
+}
+
+// DefaultTheme returns the [Theme] snapshot uses unless told otherwise, removals
+// are red and additions are green.
+func DefaultTheme() Theme {
+	return Theme{
+		Header:           hue.Bold,
+		RemovedHeader:    hue.Red,
+		AddedHeader:      hue.Green,
+		Removed:          hue.Red,
+		Added:            hue.Green,
+		RemovedHighlight: hue.Black | hue.Bold | hue.RedBackground,
+		AddedHighlight:   hue.Black | hue.Bold | hue.GreenBackground,
+		RemovedSymbol:    "-",
+		AddedSymbol:      "+",
+	}
+}
+
+// ColorblindTheme returns a [Theme] that avoids relying on a red/green distinction,
+// removals are blue and additions are yellow.
+//
+// The bright variants are used so the diff remains legible on both dark and light
+// terminal backgrounds.
+func ColorblindTheme() Theme {
+	return Theme{
+		Header:           hue.Bold,
+		RemovedHeader:    hue.BrightBlue,
+		AddedHeader:      hue.BrightYellow,
+		Removed:          hue.BrightBlue,
+		Added:            hue.BrightYellow,
+		RemovedHighlight: hue.Black | hue.Bold | hue.BrightBlueBackground,
+		AddedHighlight:   hue.Black | hue.Bold | hue.BrightYellowBackground,
+		RemovedSymbol:    "-",
+		AddedSymbol:      "+",
+	}
+}
+
+// mismatch returns the rendered diff between the old and new snapshots for
+// the snapshot stored at path, or nil if they are the same.
+//
+// If the formatter is a [Decoder], the diff is preceded by a summary of the
+// changes by path.
+func (r Runner) mismatch(path string, old, current []byte) []byte {
+	oldName, newName := "old", "new"
+	if r.layout == LayoutPatch {
+		// Name the files how git does so the patch can be applied
+		slashed := filepath.ToSlash(patchPath(path))
+		oldName, newName = "a/"+slashed, "b/"+slashed
+	}
+
+	d := diff.New(oldName, old, newName, current, diff.WithContext(r.context))
+	if d.Equal() {
+		return nil
+	}
+
+	cfg := render.Config{
+		Theme:     render.Theme(r.theme),
+		Layout:    render.Layout(r.layout),
+		Highlight: render.Highlight(r.highlight),
+		Color:     r.color,
+	}
+
+	rendered := render.Render(d, cfg)
+
+	if r.layout == LayoutPatch {
+		// Anything more and it's no longer a valid patch
+		return rendered
+	}
+
+	if paths := render.Paths(r.paths(old, current), cfg); paths != nil {
+		return append(append(paths, '\n'), rendered...)
+	}
+
+	return rendered
+}
+
+// patchPath returns path relative to the root of the git repository containing it,
+// which is what git apply resolves the paths in a patch against, rather than the
+// package directory the test is run from. If it isn't in a repository, path is
+// returned as is.
+func patchPath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+
+	for dir := filepath.Dir(abs); ; {
+		// .git is a file rather than a directory in a worktree or submodule
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			rel, err := filepath.Rel(dir, abs)
+			if err != nil {
+				return path
+			}
+
+			return rel
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return path
+		}
+
+		dir = parent
+	}
+}
+
+// paths returns the changes by path between the old and new snapshots if the
+// formatter is a [Decoder] and both can be decoded, or nil otherwise.
+func (r Runner) paths(old, current []byte) []pathdiff.Change {
+	decoder, ok := r.formatter.(Decoder)
+	if !ok {
+		return nil
+	}
+
+	before, err := decoder.Decode(old)
+	if err != nil {
+		return nil
+	}
+
+	after, err := decoder.Decode(current)
+	if err != nil {
+		return nil
+	}
+
+	return pathdiff.Compare(before, after)
+}
+
+// detectColor reports whether the diff should be rendered in colour when
+// the [Color] option has not been passed.
+//
+// It follows the same rules as hue: $FORCE_COLOR, then $NO_COLOR, then
+// $TERM, then whether [os.Stdout] is a terminal.
+func detectColor() bool {
+	if os.Getenv("FORCE_COLOR") != "" {
+		return true
+	}
+
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
+
+	return term.IsTerminal(int(os.Stdout.Fd()))
+}
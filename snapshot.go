@@ -9,10 +9,10 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strings"
 	"testing"
 
-	"go.followtheprocess.codes/diff"
-	"go.followtheprocess.codes/diff/render"
+	"go.followtheprocess.codes/snapshot/internal/format/insta"
 )
 
 const (
@@ -28,11 +28,18 @@ const (
 // It holds configuration and state for the snapshot test in question.
 type Runner struct {
 	tb          testing.TB
+	info        any
 	description string
 	formatter   Formatter
+	comparer    Comparer
 	filters     []filter
+	theme       Theme
+	context     int
+	layout      Layout
+	highlight   Highlight
 	update      bool
 	clean       bool
+	color       bool
 }
 
 // New initialises a new snapshot test [Runner].
@@ -43,7 +50,10 @@ func New(tb testing.TB, options ...Option) Runner {
 	tb.Helper()
 
 	runner := Runner{
-		tb: tb,
+		tb:      tb,
+		theme:   DefaultTheme(),
+		context: defaultContextLines,
+		color:   detectColor(),
 	}
 
 	for _, option := range options {
@@ -56,7 +66,17 @@ func New(tb testing.TB, options ...Option) Runner {
 
 	// Default to the insta formatter if none is set
 	if runner.formatter == nil {
-		runner.formatter = InstaFormatter(runner.description)
+		runner.formatter = insta.NewFormatter(runner.description, runner.info)
+	}
+
+	// If the formatter knows how to compare it's own snapshots, let it, otherwise
+	// they must be an exact match
+	if runner.comparer == nil {
+		if comparer, ok := runner.formatter.(Comparer); ok {
+			runner.comparer = comparer
+		} else {
+			runner.comparer = ExactComparer()
+		}
 	}
 
 	return runner
@@ -103,6 +123,22 @@ func (r Runner) Snap(value any) {
 	content, err := r.formatter.Format(value)
 	if err != nil {
 		r.tb.Fatalf("Snap: %v\n", err)
+
+		return
+	}
+
+	// Formatters that only encode exported fields will happily encode a struct full
+	// of unexported state as {}, and a snapshot of that can never fail
+	if lossy, ok := r.formatter.(lossy); ok {
+		if lost := lossy.Lossy(value); len(lost) != 0 {
+			r.tb.Fatalf(
+				"Snap: these values have no exported fields so would be snapshotted as {}, asserting nothing:\n\t%s\n"+
+					"Use the TextFormatter instead, or make their types a Snapshotter\n",
+				strings.Join(lost, "\n\t"),
+			)
+
+			return
+		}
 	}
 
 	// Apply any filters
@@ -142,8 +178,25 @@ func (r Runner) Snap(value any) {
 	// Normalise CRLF to LF everywhere
 	old = bytes.ReplaceAll(old, []byte("\r\n"), []byte("\n"))
 
-	if d := diff.New("old", old, "new", content); !d.Equal() {
-		r.tb.Fatalf("\nMismatch\n--------\n%s\n", render.Render(d))
+	equal, err := r.comparer.Equal(old, content)
+	if err != nil {
+		r.tb.Fatalf("Snap: could not compare snapshots: %v\n", err)
+
+		return
+	}
+
+	if !equal {
+		r.tb.Fatalf("\nMismatch\n--------\n%s\n", r.mismatch(path, old, content))
+
+		return
+	}
+
+	// The snapshot matches but it's metadata might not, which is worth knowing
+	// about but not worth failing the test over
+	if drifter, ok := r.formatter.(drifter); ok {
+		for _, drift := range drifter.Drift(old, content) {
+			r.tb.Logf("Snap: metadata for %s has changed (%s), run with Update to refresh it\n", path, drift)
+		}
 	}
 }
 
@@ -162,6 +215,22 @@ func (r Runner) Path() string {
 	return path
 }
 
+// drifter is implemented by formatters whose snapshots carry metadata that is
+// not part of the comparison, like the insta formatter, so that changes to it
+// can be reported.
+type drifter interface {
+	// Drift returns a description of each difference between the metadata
+	// of the old and current snapshots.
+	Drift(old, current []byte) []string
+}
+
+// lossy is implemented by formatters that silently drop parts of some values,
+// like those that only encode exported struct fields, so the loss can be reported.
+type lossy interface {
+	// Lossy returns a description of each part of value that would be dropped.
+	Lossy(value any) []string
+}
+
 // fileExists returns whether a path exists and is a file.
 func fileExists(path string) (bool, error) {
 	info, err := os.Stat(path)
@@ -3,9 +3,15 @@ package snapshot_test
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -206,6 +212,100 @@ func TestUpdate(t *testing.T) {
 	})
 }
 
+func TestExpression(t *testing.T) {
+	// expression returns the expression recorded in the snapshot at path
+	expression := func(t *testing.T, path string) string {
+		t.Helper()
+
+		content, err := os.ReadFile(path)
+		test.Ok(t, err)
+
+		for line := range strings.Lines(string(content)) {
+			if rest, ok := strings.CutPrefix(line, "expression: "); ok {
+				return strings.TrimSpace(rest)
+			}
+		}
+
+		return ""
+	}
+
+	// source returns the source file recorded in the snapshot at path
+	source := func(t *testing.T, path string) string {
+		t.Helper()
+
+		content, err := os.ReadFile(path)
+		test.Ok(t, err)
+
+		for line := range strings.Lines(string(content)) {
+			if rest, ok := strings.CutPrefix(line, "source: "); ok {
+				return strings.TrimSpace(rest)
+			}
+		}
+
+		return ""
+	}
+
+	value := []int{1, 2, 3}
+
+	t.Run("direct", func(t *testing.T) {
+		snap := snapshot.New(t, snapshot.Update(true))
+		snap.Snap(value)
+
+		test.Equal(t, expression(t, snap.Path()), "value")
+	})
+
+	t.Run("helper", func(t *testing.T) {
+		snap := snapshot.New(t, snapshot.Update(true))
+		assertSnap(t, snap, value)
+
+		test.Equal(t, expression(t, snap.Path()), "assertSnap(t, snap, value)")
+	})
+
+	t.Run("helper subtest", func(t *testing.T) {
+		// The test function is never skipped as a helper, that would be the
+		// testing package
+		t.Helper()
+
+		snap := snapshot.New(t, snapshot.Update(true))
+		snap.Snap(value)
+
+		test.Equal(t, expression(t, snap.Path()), "value")
+		test.Equal(t, source(t, snap.Path()), "snapshot_test.go")
+	})
+
+	t.Run("method value", func(t *testing.T) {
+		snap := snapshot.New(t, snapshot.Update(true))
+		check := snap.Snap
+		check(value)
+
+		test.Equal(t, expression(t, snap.Path()), "value")
+	})
+
+	t.Run("multi line", func(t *testing.T) {
+		snap := snapshot.New(t, snapshot.Update(true))
+		snap.Snap(
+			value,
+		)
+
+		test.Equal(t, expression(t, snap.Path()), "value")
+	})
+
+	t.Run("unresolved", func(t *testing.T) {
+		// Nothing on the line is a call of Snap, so rather than guess from the
+		// arguments of some other call, there's no expression
+		snap := snapshot.New(t, snapshot.Update(true))
+		[]func(any){snap.Snap}[0](value)
+
+		test.Equal(t, expression(t, snap.Path()), "")
+	})
+}
+
+// assertSnap is a test helper wrapping Snap.
+func assertSnap(tb testing.TB, snap snapshot.Runner, value any) {
+	tb.Helper()
+	snap.Snap(value)
+}
+
 func TestClean(t *testing.T) {
 	// Have it in it's own directory
 	t.Run("clean", func(t *testing.T) {
@@ -250,6 +350,226 @@ func TestClean(t *testing.T) {
 	})
 }
 
+func TestColor(t *testing.T) {
+	tests := []struct {
+		name  string // Name of the test case
+		color bool   // Whether colour is enabled for the runner
+	}{
+		{
+			name:  "enabled",
+			color: true,
+		},
+		{
+			name:  "disabled",
+			color: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			tb := &TB{out: buf, name: t.Name()}
+
+			snap := snapshot.New(
+				tb,
+				snapshot.Color(tt.color),
+				snapshot.WithTheme(snapshot.ColorblindTheme()),
+				snapshot.WithFormatter(snapshot.TextFormatter()),
+			)
+
+			// Make sure the existing snapshot is the one we expect
+			test.Ok(t, os.RemoveAll(snap.Path()))
+			snap.Snap("existing")
+
+			snap.Snap("different")
+
+			test.True(t, tb.failed, test.Context("snapshot should have failed"))
+
+			// Each runner gets its own colour setting, nothing is shared
+			got := strings.Contains(buf.String(), "\x1b[")
+			test.Equal(t, got, tt.color, test.Context("output:\n%s", buf.String()))
+		})
+	}
+}
+
+func TestLayoutPatch(t *testing.T) {
+	git, err := exec.LookPath("git")
+	if err != nil {
+		t.Skip("git is needed to check the patch applies")
+	}
+
+	// A package below the root of a repository, where paths relative to the
+	// package would be skipped by git apply
+	repo := t.TempDir()
+	pkg := filepath.Join(repo, "internal", "pkg")
+	test.Ok(t, os.MkdirAll(pkg, 0o755))
+
+	gitInit := exec.CommandContext(t.Context(), git, "init", "--quiet", repo)
+	out, err := gitInit.CombinedOutput()
+	test.Ok(t, err, test.Context("git init: %s", out))
+
+	t.Chdir(pkg)
+
+	buf := &bytes.Buffer{}
+	tb := &TB{out: buf, name: t.Name()}
+
+	snap := snapshot.New(
+		tb,
+		snapshot.Color(true),
+		snapshot.WithLayout(snapshot.LayoutPatch),
+		snapshot.WithFormatter(snapshot.TextFormatter()),
+	)
+
+	snap.Snap("existing\n")
+	snap.Snap("different\n")
+
+	test.True(t, tb.failed, test.Context("snapshot should have failed"))
+
+	// The patch must be plain and name the snapshot file from the repository root
+	patch := buf.String()[strings.Index(buf.String(), "diff --git"):]
+	want := "diff --git a/internal/pkg/testdata/snapshots/TestLayoutPatch.snap.txt " +
+		"b/internal/pkg/testdata/snapshots/TestLayoutPatch.snap.txt\n" +
+		"--- a/internal/pkg/testdata/snapshots/TestLayoutPatch.snap.txt\n" +
+		"+++ b/internal/pkg/testdata/snapshots/TestLayoutPatch.snap.txt\n" +
+		"@@ -1,1 +1,1 @@\n" +
+		"-existing\n" +
+		"+different\n"
+
+	test.True(t, strings.HasPrefix(patch, want), test.Context("output:\n%s", buf.String()))
+
+	// And git must actually apply it from the package directory, it skips patches
+	// to files it can't find without failing so check it said it would apply
+	check := exec.CommandContext(t.Context(), git, "apply", "--check", "--verbose")
+	check.Stdin = strings.NewReader(patch)
+
+	out, err = check.CombinedOutput()
+	test.Ok(t, err, test.Context("git apply: %s", out))
+	test.True(t, strings.Contains(string(out), "Checking patch"), test.Context("git apply: %s", out))
+	test.False(t, strings.Contains(string(out), "Skipped patch"), test.Context("git apply: %s", out))
+}
+
+func TestPaths(t *testing.T) {
+	type user struct {
+		Name  string `json:"name"`
+		Email string `json:"email"`
+	}
+
+	buf := &bytes.Buffer{}
+	tb := &TB{out: buf, name: t.Name()}
+
+	snap := snapshot.New(
+		tb,
+		snapshot.Color(false),
+		snapshot.WithFormatter(snapshot.JSONFormatter()),
+	)
+
+	// Make sure the existing snapshot is the one we expect
+	test.Ok(t, os.RemoveAll(snap.Path()))
+	snap.Snap([]user{{Name: "a", Email: "a@x"}, {Name: "b", Email: "a@x"}})
+
+	snap.Snap([]user{{Name: "a", Email: "a@x"}, {Name: "b", Email: "b@x"}, {Name: "c", Email: "c@x"}})
+
+	test.True(t, tb.failed, test.Context("snapshot should have failed"))
+
+	want := ".[1].email: \"a@x\" → \"b@x\"\n.: 1 element added\n"
+	test.True(t, strings.Contains(buf.String(), want), test.Context("output:\n%s", buf.String()))
+}
+
+func TestComparer(t *testing.T) {
+	tests := []struct {
+		comparer snapshot.Comparer // The comparer to use
+		name     string            // Name of the test case
+		existing string            // Contents of the existing snapshot file
+		wantFail bool              // Whether we want the test to fail
+	}{
+		{
+			name:     "exact",
+			comparer: snapshot.ExactComparer(),
+			existing: `{"b": 2, "a": 1}`,
+			wantFail: true,
+		},
+		{
+			name:     "json",
+			comparer: snapshot.JSONComparer(),
+			existing: `{"b": 2, "a": 1}`,
+			wantFail: false,
+		},
+		{
+			name:     "json invalid",
+			comparer: snapshot.JSONComparer(),
+			existing: `{"b": 2, `,
+			wantFail: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			tb := &TB{out: buf, name: t.Name()}
+
+			snap := snapshot.New(
+				tb,
+				snapshot.WithComparer(tt.comparer),
+				snapshot.WithFormatter(snapshot.JSONFormatter()),
+			)
+
+			// Simulate a hand edited snapshot file
+			test.Ok(t, os.MkdirAll(filepath.Dir(snap.Path()), 0o755))
+			test.Ok(t, os.WriteFile(snap.Path(), []byte(tt.existing), 0o644))
+
+			snap.Snap(map[string]int{"a": 1, "b": 2})
+
+			if tb.failed != tt.wantFail {
+				t.Fatalf(
+					"\ntb.failed = %v\ntt.wantFail = %v\n\noutput:\n\n%s\n",
+					tb.failed,
+					tt.wantFail,
+					buf.String(),
+				)
+			}
+		})
+	}
+}
+
+func TestInvalidOptions(t *testing.T) {
+	tests := []struct {
+		name   string          // Name of the test case
+		option snapshot.Option // The invalid option
+	}{
+		{
+			name:   "theme without symbols",
+			option: snapshot.WithTheme(snapshot.Theme{}),
+		},
+		{
+			name:   "negative context lines",
+			option: snapshot.ContextLines(-1),
+		},
+		{
+			name:   "unknown layout",
+			option: snapshot.WithLayout(snapshot.Layout(42)),
+		},
+		{
+			name:   "nil comparer",
+			option: snapshot.WithComparer(nil),
+		},
+		{
+			name:   "unknown highlight",
+			option: snapshot.WithHighlight(snapshot.Highlight(-1)),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			tb := &TB{out: buf, name: t.Name()}
+
+			snapshot.New(tb, tt.option)
+
+			test.True(t, tb.failed, test.Context("invalid option should fail the test"))
+		})
+	}
+}
+
 type customFormatter struct{}
 
 // Implement formatter.
@@ -262,6 +582,35 @@ func (c customFormatter) Ext() string {
 	return ".custom.txt"
 }
 
+func TestLossy(t *testing.T) {
+	type counter struct {
+		Name  string       `json:"name"`
+		Count atomic.Int64 `json:"count"`
+	}
+
+	buf := &bytes.Buffer{}
+	tb := &TB{out: buf, name: t.Name()}
+
+	snap := snapshot.New(
+		tb,
+		snapshot.Color(false),
+		snapshot.WithFormatter(snapshot.JSONFormatter()),
+	)
+
+	value := &counter{Name: "requests"}
+	value.Count.Store(42)
+
+	// Count is encoded as {} so the snapshot can't catch it changing, that
+	// must fail rather than silently pass, and never be written
+	snap.Snap(value)
+
+	test.True(t, tb.failed, test.Context("snapshot should have failed"))
+	test.True(t, strings.Contains(buf.String(), ".Count (atomic.Int64)"), test.Context("output:\n%s", buf.String()))
+
+	_, err := os.Stat(snap.Path())
+	test.True(t, errors.Is(err, fs.ErrNotExist), test.Context("snapshot should not have been written"))
+}
+
 func TestFormatter(t *testing.T) {
 	custom := customFormatter{}
 	snap := snapshot.New(t, snapshot.WithFormatter(custom))